@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// accessibilityChecks follows a page's tokens to find images without alt text, links without text,
+// form fields without labels and a missing lang attribute
+type accessibilityChecks struct {
+	issues   []string
+	labelFor map[string]struct{} //ids that a <label for> names
+	inLabel  int                 //how many <label> tags deep we are
+	fields   []formField         //fields not labelled by where they are or their attributes, which a <label for> may still name
+}
+
+type formField struct {
+	tag, name, id string
+}
+
+func newAccessibilityChecks() *accessibilityChecks {
+	return &accessibilityChecks{labelFor: make(map[string]struct{})}
+}
+
+func (a *accessibilityChecks) token(tokenType html.TokenType, token html.Token) {
+	if tokenType == html.EndTagToken {
+		if token.DataAtom == atom.Label && a.inLabel > 0 {
+			a.inLabel--
+		}
+		return
+	}
+	if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+		return
+	}
+	switch token.DataAtom {
+	case atom.Img:
+		if !hasAttr(token, "alt") && !hasAttr(token, "aria-label") && attrValue(token, "role") != "presentation" {
+			a.issues = append(a.issues, fmt.Sprintf("image %s has no alt attribute", attrValue(token, "src")))
+		}
+	case atom.Label:
+		if tokenType == html.StartTagToken {
+			a.inLabel++
+		}
+		if id := attrValue(token, "for"); id != "" {
+			a.labelFor[id] = struct{}{}
+		}
+	case atom.Input, atom.Select, atom.Textarea:
+		switch strings.ToLower(attrValue(token, "type")) {
+		case "hidden", "submit", "button", "reset", "image": //labelled by their value or alt, or not shown at all
+			return
+		}
+		if a.inLabel > 0 || hasAttr(token, "aria-label") || hasAttr(token, "aria-labelledby") || hasAttr(token, "title") {
+			return
+		}
+		a.fields = append(a.fields, formField{tag: token.Data, name: attrValue(token, "name"), id: attrValue(token, "id")})
+	}
+}
+
+// finish records the issues found on the page, along with those only knowable once it has all been seen
+func (a *accessibilityChecks) finish(target *Page) {
+	if (*target).Lang == "" {
+		a.issues = append(a.issues, "html tag has no lang attribute")
+	}
+	for _, anchor := range (*target).Anchors {
+		if anchor.Text == "" && !anchor.labelled {
+			a.issues = append(a.issues, fmt.Sprintf("link to %s has no text", anchor.URL))
+		}
+	}
+	for _, field := range a.fields {
+		if _, ok := a.labelFor[field.id]; field.id != "" && ok {
+			continue
+		}
+		a.issues = append(a.issues, fmt.Sprintf("%s %q has no label", field.tag, field.name))
+	}
+	(*target).AccessibilityIssues = a.issues
+}
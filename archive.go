@@ -0,0 +1,141 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveEntry is one file in an archive's manifest.json
+type ArchiveEntry struct {
+	Path        string `json:"path"`
+	Kind        string `json:"kind"` //report, metadata or body
+	URL         string `json:"url,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// Archive packages a crawl into one .zip or .tar.gz for handing off: the report, every page's record as
+// pages.ndjson, every successfully fetched body under bodies/, and a manifest.json indexing them all.
+// Bodies are added as they are fetched, the rest once the crawl is done.
+type Archive struct {
+	f     *os.File
+	gz    *gzip.Writer
+	tw    *tar.Writer
+	zw    *zip.Writer
+	mutex sync.Mutex
+
+	manifest []ArchiveEntry
+	bodies   map[string]string //archive path of each body by content hash, so identical bodies are stored once
+}
+
+func NewArchive(path string) (*Archive, error) {
+	isZip := strings.HasSuffix(path, ".zip")
+	if !isZip && !strings.HasSuffix(path, ".tar.gz") && !strings.HasSuffix(path, ".tgz") {
+		return nil, fmt.Errorf("archive %s should end in .zip, .tar.gz or .tgz", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{f: f, bodies: make(map[string]string)}
+	if isZip {
+		a.zw = zip.NewWriter(f)
+	} else {
+		a.gz = gzip.NewWriter(f)
+		a.tw = tar.NewWriter(a.gz)
+	}
+	return a, nil
+}
+
+// add writes one file into the archive, the caller holding the mutex
+func (a *Archive) add(entry ArchiveEntry, data []byte) error {
+	entry.Size = int64(len(data))
+	if a.zw != nil {
+		w, err := a.zw.CreateHeader(&zip.FileHeader{Name: entry.Path, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	} else {
+		if err := a.tw.WriteHeader(&tar.Header{Name: entry.Path, Mode: 0644, Size: entry.Size, ModTime: time.Now()}); err != nil {
+			return err
+		}
+		if _, err := a.tw.Write(data); err != nil {
+			return err
+		}
+	}
+	a.manifest = append(a.manifest, entry)
+	return nil
+}
+
+// savePage adds a successfully fetched page's body
+func (a *Archive) savePage(target *Page, data []byte) {
+	if (*target).Status != http.StatusOK {
+		return
+	}
+	entry := ArchiveEntry{Kind: "body", URL: (*target).URL.String(), Status: (*target).Status, ContentType: (*target).ContentType}
+	hash := contentHash(data)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if path, ok := a.bodies[hash]; ok { //listed again for this URL, pointing at the copy already stored
+		entry.Path, entry.Size = path, int64(len(data))
+		a.manifest = append(a.manifest, entry)
+		return
+	}
+	entry.Path = "bodies/" + hash
+	if err := a.add(entry, data); err != nil {
+		log.Errorf("failed to archive %s: %v", (*target).URL.String(), err)
+		return
+	}
+	a.bodies[hash] = entry.Path
+}
+
+// Close adds the report in the given format and the pages' records, then the manifest, and finishes the archive
+func (a *Archive) Close(pages []*Page, format string, write func(io.Writer, []*Page) error) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var report, metadata bytes.Buffer
+	if err := write(&report, pages); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if err := writeNDJSON(&metadata, page); err != nil {
+			return err
+		}
+	}
+	if err := a.add(ArchiveEntry{Path: "report." + format, Kind: "report"}, report.Bytes()); err != nil {
+		return err
+	}
+	if err := a.add(ArchiveEntry{Path: "pages.ndjson", Kind: "metadata"}, metadata.Bytes()); err != nil {
+		return err
+	}
+	manifest, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := a.add(ArchiveEntry{Path: "manifest.json", Kind: "metadata"}, manifest); err != nil {
+		return err
+	}
+	if a.zw != nil {
+		err = a.zw.Close()
+	} else if err = a.tw.Close(); err == nil {
+		err = a.gz.Close()
+	}
+	if closeErr := a.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
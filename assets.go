@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Asset is what checking one of the statics pages refer to found, shared by every page referring to it
+type Asset struct {
+	URL         string `json:"url"`
+	Status      int    `json:"status,omitempty"`
+	Size        int64  `json:"size,omitempty"` //in bytes, 0 if the server didn't say
+	ContentType string `json:"content_type,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	once sync.Once
+}
+
+// broken reports whether the asset couldn't be fetched
+func (a *Asset) broken() bool {
+	return a.Error != "" || a.Status >= 400
+}
+
+// kind sorts an asset into image, script, stylesheet, font or other, by content type or failing that its extension
+func (a *Asset) kind() string {
+	contentType := a.ContentType
+	if contentType == "" {
+		if u, err := url.Parse(a.URL); err == nil {
+			contentType = mime.TypeByExtension(path.Ext(u.Path))
+		}
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+	switch contentType = strings.TrimSpace(strings.ToLower(contentType)); {
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	case strings.Contains(contentType, "javascript"), strings.Contains(contentType, "ecmascript"):
+		return "script"
+	case contentType == "text/css":
+		return "stylesheet"
+	case strings.HasPrefix(contentType, "font/"), strings.Contains(contentType, "font"):
+		return "font"
+	}
+	return "other"
+}
+
+// checkAssets HEADs each of a page's statics, once per crawl however many pages refer to it
+func (c *Crawler) checkAssets(ctx context.Context, target *Page) {
+	(*target).Assets = c.checkURLs(ctx, (*target).Statics)
+}
+
+// checkExternal HEADs each of the links a page has out of the crawl's scope, once per crawl like checkAssets
+func (c *Crawler) checkExternal(ctx context.Context, target *Page) {
+	var links []*url.URL
+	for _, link := range (*target).ExternalLinks {
+		if !isOnion(link) || c.Onion { //looking these up without Tor leaks them
+			links = append(links, link)
+		}
+	}
+	(*target).External = c.checkURLs(ctx, links)
+}
+
+func (c *Crawler) checkURLs(ctx context.Context, urls []*url.URL) []*Asset {
+	var checked []*Asset
+	for _, u := range urls {
+		c.mutex.Lock()
+		asset, ok := c.assets[u.String()]
+		if !ok {
+			asset = &Asset{URL: u.String()}
+			c.assets[u.String()] = asset
+		}
+		c.mutex.Unlock()
+		asset.once.Do(func() { c.checkAsset(ctx, u, asset) }) //waits for whoever got there first
+		checked = append(checked, asset)
+	}
+	return checked
+}
+
+func (c *Crawler) checkAsset(ctx context.Context, u *url.URL, asset *Asset) {
+	release, ok := c.acquireHost(ctx, u)
+	if !ok {
+		return
+	}
+	defer release()
+	resp, err := c.fetchAsset(ctx, "HEAD", u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = c.fetchAsset(ctx, "GET", u) //for servers that don't do HEAD
+	}
+	if err != nil {
+		asset.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	asset.Status, asset.ContentType = resp.StatusCode, resp.Header.Get("Content-Type")
+	asset.Size = max(resp.ContentLength, 0)
+	if resp.Request.Method == "GET" && resp.ContentLength < 0 {
+		asset.Size, _ = io.Copy(io.Discard, resp.Body)
+	}
+}
+
+func (c *Crawler) fetchAsset(ctx context.Context, method string, u *url.URL) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, u.String())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if method == "HEAD" {
+		resp.Body.Close()
+	}
+	return resp, nil
+}
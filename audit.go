@@ -0,0 +1,459 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AuditOptions are the thresholds audits flag pages against
+type AuditOptions struct {
+	WeightBudget      int64         //bytes a page and its statics may add up to, 0 for no budget
+	MaxRedirectHops   int           //longest redirect chain that isn't a problem in itself
+	CertExpiryWarning time.Duration //how soon a certificate can expire before it is a problem
+	TopPages          int           //how many pages ranked reports list at each end
+	MaxClickDepth     int           //most clicks from the seed a page can be before it is a problem
+}
+
+// audits maps a -audit name to the function that writes that report on a crawled site map, in place of the webmap
+var audits = map[string]func(io.Writer, *Page, AuditOptions) error{
+	"seo":           writeSEOAudit,
+	"assets":        writeAssetAudit,
+	"weight":        writeWeightAudit,
+	"redirects":     writeRedirectAudit,
+	"mixed":         writeMixedContentAudit,
+	"security":      writeSecurityHeaderAudit,
+	"tls":           writeTLSAudit,
+	"accessibility": writeAccessibilityAudit,
+	"duplicates":    writeDuplicateAudit,
+	"orphans":       writeOrphanAudit,
+	"links":         writeLinkAudit,
+	"depth":         writeClickDepthAudit,
+	"cycles":        writeCycleAudit,
+	"external":      writeExternalAudit,
+}
+
+// auditsCheckingAccessibility are the audits that need the crawl to run accessibility checks
+var auditsCheckingAccessibility = map[string]bool{"accessibility": true}
+
+// auditsFetchingSitemaps are the audits that need each seed's sitemap
+var auditsFetchingSitemaps = map[string]bool{"orphans": true}
+
+// auditsCheckingExternal are the audits that need every external link checked
+var auditsCheckingExternal = map[string]bool{"external": true}
+
+// auditsCheckingStatics are the audits that need every static checked
+var auditsCheckingStatics = map[string]bool{"assets": true, "weight": true}
+
+func auditNames() []string {
+	names := make([]string, 0, len(audits))
+	for name := range audits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maxTitleLength is about where search results start truncating titles
+const maxTitleLength = 60
+
+// sitePages lists every page under root once, parents before their links
+func sitePages(root *Page) []*Page {
+	var pages []*Page
+	seen := make(map[*Page]struct{})
+	var walk func(*Page)
+	walk = func(page *Page) {
+		if _, ok := seen[page]; ok {
+			return
+		}
+		seen[page] = struct{}{}
+		pages = append(pages, page)
+		for _, link := range page.Links {
+			walk(link)
+		}
+	}
+	walk(root)
+	return pages
+}
+
+// writeAuditReport writes each page's problems under its URL, leaving out pages without any
+func writeAuditReport(w io.Writer, pages []*Page, problems map[*Page][]string) error {
+	for _, page := range pages {
+		if len(problems[page]) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, displayURL(page.URL.String())); err != nil {
+			return err
+		}
+		for _, problem := range problems[page] {
+			if _, err := fmt.Fprintln(w, "    "+problem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSEOAudit reports missing, duplicate and overlong titles, missing descriptions, multiple h1s and noindex pages
+func writeSEOAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	var pages []*Page
+	byTitle := make(map[string][]*Page)
+	for _, page := range sitePages(root) {
+		if !page.isHTML() || page.Status < 200 || page.Status > 299 { //only pages that were successfully parsed have anything to check
+			continue
+		}
+		pages = append(pages, page)
+		if page.Title != "" {
+			byTitle[page.Title] = append(byTitle[page.Title], page)
+		}
+	}
+	problems := make(map[*Page][]string)
+	for _, page := range pages {
+		switch length := utf8.RuneCountInString(page.Title); {
+		case length == 0:
+			problems[page] = append(problems[page], "missing title")
+		case length > maxTitleLength:
+			problems[page] = append(problems[page], fmt.Sprintf("title is %d characters, over %d", length, maxTitleLength))
+		}
+		if others := byTitle[page.Title]; len(others) > 1 {
+			for _, other := range others {
+				if other != page {
+					problems[page] = append(problems[page], fmt.Sprintf("duplicate title %q, also on %s", page.Title, other.URL))
+				}
+			}
+		}
+		if page.Description == "" {
+			problems[page] = append(problems[page], "missing meta description")
+		}
+		if len(page.H1s) > 1 {
+			problems[page] = append(problems[page], fmt.Sprintf("%d h1 tags", len(page.H1s)))
+		}
+		if slices.Contains(page.Robots, "noindex") || slices.Contains(page.Robots, "none") {
+			problems[page] = append(problems[page], "noindex")
+		}
+	}
+	return writeAuditReport(w, pages, problems)
+}
+
+// writeAssetAudit reports every broken static, under each page that refers to it. It needs the crawl to have checked statics.
+func writeAssetAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	pages := sitePages(root)
+	problems := make(map[*Page][]string)
+	for _, page := range pages {
+		for _, asset := range page.Assets {
+			switch {
+			case asset.Error != "":
+				problems[page] = append(problems[page], fmt.Sprintf("%s failed: %s", asset.URL, asset.Error))
+			case asset.broken():
+				problems[page] = append(problems[page], fmt.Sprintf("%s returned status %d", asset.URL, asset.Status))
+			}
+		}
+	}
+	return writeAuditReport(w, pages, problems)
+}
+
+// weightLargestAssets is how many of each page's biggest statics the weight audit lists
+const weightLargestAssets = 3
+
+// writeWeightAudit reports each page's total size with its statics, how many statics of each type it has
+// and its largest ones, flagging pages over the budget. It needs the crawl to have checked statics.
+func writeWeightAudit(w io.Writer, root *Page, opts AuditOptions) error {
+	for _, page := range sitePages(root) {
+		if !page.isHTML() {
+			continue
+		}
+		total := page.Size
+		counts := make(map[string]int)
+		for _, asset := range page.Assets {
+			total += asset.Size
+			counts[asset.kind()]++
+		}
+		line := fmt.Sprintf("%s  %s", displayURL(page.URL.String()), formatBytes(total))
+		if opts.WeightBudget > 0 && total > opts.WeightBudget {
+			line += fmt.Sprintf("  OVER BUDGET of %s", formatBytes(opts.WeightBudget))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		kinds := make([]string, 0, len(counts))
+		for kind := range counts {
+			kinds = append(kinds, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+		sort.Strings(kinds)
+		if len(kinds) == 0 {
+			kinds = []string{"none"}
+		}
+		if _, err := fmt.Fprintf(w, "    page %s, statics: %s\n", formatBytes(page.Size), strings.Join(kinds, ", ")); err != nil {
+			return err
+		}
+		largest := slices.Clone(page.Assets)
+		sort.SliceStable(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+		for _, asset := range largest[:min(len(largest), weightLargestAssets)] {
+			if asset.Size == 0 {
+				break
+			}
+			if _, err := fmt.Fprintf(w, "    %s  %s\n", formatBytes(asset.Size), asset.URL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// writeRedirectAudit reports, under each page, the links on it that point at redirects rather than where they end up,
+// and pages whose own redirect chain is longer than the options allow
+func writeRedirectAudit(w io.Writer, root *Page, opts AuditOptions) error {
+	pages := sitePages(root)
+	byURL := make(map[string]*Page, len(pages))
+	for _, page := range pages {
+		byURL[page.URL.String()] = page
+	}
+	problems := make(map[*Page][]string)
+	for _, page := range pages {
+		if hops := len(page.Redirects); hops > opts.MaxRedirectHops {
+			problems[page] = append(problems[page], fmt.Sprintf("redirect chain of %d hops: %s", hops, formatChain(page.Redirects)))
+		}
+		reported := make(map[string]struct{})
+		for _, anchor := range page.Anchors {
+			linked, ok := byURL[anchor.URL]
+			if _, done := reported[anchor.URL]; done || !ok || len(linked.Redirects) == 0 {
+				continue
+			}
+			reported[anchor.URL] = struct{}{}
+			final := linked.Redirects[len(linked.Redirects)-1].To
+			problems[page] = append(problems[page], fmt.Sprintf("links to %s, which redirects to %s", anchor.URL, final))
+		}
+	}
+	return writeAuditReport(w, pages, problems)
+}
+
+func formatChain(chain []Redirect) string {
+	hops := []string{chain[0].From}
+	for _, hop := range chain {
+		hops = append(hops, fmt.Sprintf("(%d) %s", hop.Status, hop.To))
+	}
+	return strings.Join(hops, " -> ")
+}
+
+// writeMixedContentAudit lists, under each https page, the subresources it loads over plain http
+func writeMixedContentAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	pages := sitePages(root)
+	problems := make(map[*Page][]string)
+	for _, page := range pages {
+		for _, ref := range page.MixedContent {
+			problems[page] = append(problems[page], "loads "+ref+" over http")
+		}
+	}
+	return writeAuditReport(w, pages, problems)
+}
+
+// writeSecurityHeaderAudit summarises how many pages lack each security header, then lists the ones missing from each page.
+// Strict-Transport-Security only counts against https pages, as browsers ignore it over http.
+func writeSecurityHeaderAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	var pages []*Page
+	for _, page := range sitePages(root) {
+		if !page.Fetched.IsZero() {
+			pages = append(pages, page)
+		}
+	}
+	problems := make(map[*Page][]string)
+	for _, name := range securityHeaders {
+		missing, applicable := 0, 0
+		for _, page := range pages {
+			if name == "Strict-Transport-Security" && page.URL.Scheme != "https" {
+				continue
+			}
+			applicable++
+			if _, ok := page.SecurityHeaders[name]; !ok {
+				missing++
+				problems[page] = append(problems[page], "missing "+name)
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s missing on %d/%d pages\n", name, missing, applicable); err != nil {
+			return err
+		}
+	}
+	return writeAuditReport(w, pages, problems)
+}
+
+// writeTLSAudit describes each https host's connection and certificate chain, with warnings about weak protocols and expiring certificates
+func writeTLSAudit(w io.Writer, root *Page, opts AuditOptions) error {
+	seen := make(map[*TLSInfo]struct{})
+	now := time.Now()
+	for _, page := range sitePages(root) {
+		info := page.TLS
+		if info == nil {
+			continue
+		}
+		if _, ok := seen[info]; ok {
+			continue
+		}
+		seen[info] = struct{}{}
+		lines := []string{fmt.Sprintf("%s  %s  %s", info.Host, info.Version, info.CipherSuite)}
+		for _, warning := range info.warnings(now, opts.CertExpiryWarning) {
+			lines = append(lines, "    WARNING "+warning)
+		}
+		for _, cert := range info.Certificates {
+			lines = append(lines, fmt.Sprintf("    %s, issued by %s, valid %s to %s", cert.Subject, cert.Issuer, cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly)))
+			if len(cert.DNSNames) > 0 {
+				lines = append(lines, "        for "+strings.Join(cert.DNSNames, ", "))
+			}
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeAccessibilityAudit summarises how many pages have accessibility issues, then lists each page's
+func writeAccessibilityAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	var pages []*Page
+	problems := make(map[*Page][]string)
+	issues := 0
+	for _, page := range sitePages(root) {
+		if page.isHTML() {
+			pages = append(pages, page)
+			problems[page] = page.AccessibilityIssues
+			issues += len(page.AccessibilityIssues)
+		}
+	}
+	withIssues := 0
+	for _, page := range pages {
+		if len(problems[page]) > 0 {
+			withIssues++
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%d issues on %d/%d pages\n", issues, withIssues, len(pages)); err != nil {
+		return err
+	}
+	return writeAuditReport(w, pages, problems)
+}
+
+// writeOrphanAudit compares the seed's sitemap with the pages the crawl found by following links,
+// listing sitemap URLs no link led to and found pages missing from the sitemap
+func writeOrphanAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	if root.Sitemap == nil {
+		_, err := fmt.Fprintf(w, "no sitemap found for %s\n", root.URL)
+		return err
+	}
+	found := make(map[string]struct{})
+	var unlisted []string
+	inSitemap := make(map[string]struct{}, len(root.Sitemap))
+	for _, rawURL := range root.Sitemap {
+		if u, err := url.Parse(rawURL); err == nil {
+			inSitemap[normaliseURL(u)] = struct{}{}
+		}
+	}
+	for _, page := range sitePages(root) {
+		key := normaliseURL(page.URL)
+		found[key] = struct{}{}
+		if _, ok := inSitemap[key]; !ok && page.isHTML() && page.Status >= 200 && page.Status <= 299 {
+			unlisted = append(unlisted, page.URL.String())
+		}
+	}
+	var orphans []string
+	for _, rawURL := range root.Sitemap {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		if _, ok := found[normaliseURL(u)]; !ok {
+			orphans = append(orphans, rawURL)
+		}
+	}
+	for _, section := range []struct {
+		heading string
+		urls    []string
+	}{
+		{"In the sitemap but not linked to within the crawl's depth:", orphans},
+		{"Linked to but not in the sitemap:", unlisted},
+	} {
+		if len(section.urls) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, section.heading); err != nil {
+			return err
+		}
+		for _, u := range section.urls {
+			if _, err := fmt.Fprintln(w, "    "+u); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeExternalAudit lists every link out of the crawl's scope with the pages linking to it, broken ones first with what checking them found
+func writeExternalAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	sources := make(map[string][]string)
+	checked := make(map[string]*Asset)
+	for _, page := range sitePages(root) {
+		for _, link := range page.ExternalLinks {
+			sources[link.String()] = append(sources[link.String()], page.URL.String())
+		}
+		for _, asset := range page.External {
+			checked[asset.URL] = asset
+		}
+	}
+	var broken, working []string
+	for rawURL := range sources {
+		if asset, ok := checked[rawURL]; ok && asset.broken() {
+			broken = append(broken, rawURL)
+		} else {
+			working = append(working, rawURL)
+		}
+	}
+	sort.Strings(broken)
+	sort.Strings(working)
+	for _, section := range []struct {
+		heading string
+		urls    []string
+	}{
+		{"Broken external links:", broken},
+		{"External links:", working},
+	} {
+		if len(section.urls) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, section.heading); err != nil {
+			return err
+		}
+		for _, rawURL := range section.urls {
+			line := rawURL
+			if asset, ok := checked[rawURL]; ok {
+				if asset.Error != "" {
+					line += " (failed: " + asset.Error + ")"
+				} else {
+					line += fmt.Sprintf(" (status %d)", asset.Status)
+				}
+			}
+			if _, err := fmt.Fprintln(w, "    "+line); err != nil {
+				return err
+			}
+			for _, source := range sources[rawURL] {
+				if _, err := fmt.Fprintln(w, "        from "+source); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
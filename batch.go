@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batcher collects a sink's writes, handing them to flush in batches of up to size, and every interval whatever a
+// part-filled batch has, so no page waits on a quiet crawl for long
+type batcher[T any] struct {
+	size  int
+	flush func(context.Context, []T) error
+	what  string //what flushing does, for logging its failures in the background
+
+	mutex sync.Mutex
+	batch []T
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newBatcher[T any](size int, interval time.Duration, what string, flush func(context.Context, []T) error) *batcher[T] {
+	b := &batcher[T]{size: size, flush: flush, what: what, stop: make(chan struct{}), done: make(chan struct{})}
+	go b.flushPeriodically(interval)
+	return b
+}
+
+// add batches item, flushing the batch once it's full
+func (b *batcher[T]) add(ctx context.Context, item T) error {
+	b.mutex.Lock()
+	b.batch = append(b.batch, item)
+	full := len(b.batch) >= b.size
+	b.mutex.Unlock()
+	if full {
+		return b.flushBatch(ctx)
+	}
+	return nil
+}
+
+func (b *batcher[T]) flushPeriodically(interval time.Duration) {
+	defer close(b.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			if err := b.flushBatch(context.Background()); err != nil {
+				log.Errorf("failed to %s: %v", b.what, err)
+			}
+		}
+	}
+}
+
+// flushBatch hands everything batched so far to flush
+func (b *batcher[T]) flushBatch(ctx context.Context) error {
+	b.mutex.Lock()
+	batch := b.batch
+	b.batch = nil
+	b.mutex.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	return b.flush(ctx, batch)
+}
+
+// close stops the background flushing and flushes whatever is left
+func (b *batcher[T]) close() error {
+	close(b.stop)
+	<-b.done
+	return b.flushBatch(context.Background())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BodyStore keeps each fetched body gzipped under bodyKey of its URL, so pages can be parsed again later without refetching
+type BodyStore interface {
+	Put(ctx context.Context, key, contentType string, body []byte) error
+	Ping(ctx context.Context) error //reports why the store can't be written to, if it can't
+}
+
+// bodyKey is the name a page's body is stored under: the sha256 of its URL, fanned out by its first byte so no one directory gets huge
+func bodyKey(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	hash := hex.EncodeToString(sum[:])
+	return hash[:2] + "/" + hash + ".gz"
+}
+
+// openBodyStore opens the store described by s3://bucket/prefix, gs://bucket/prefix or a local directory
+func openBodyStore(rawURL string) (BodyStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "s3", "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("body store %q needs a bucket", rawURL)
+		}
+		client, err := newObjectClient(u)
+		if err != nil {
+			return nil, err
+		}
+		return &objectBodyStore{client: client, bucket: u.Host, prefix: strings.Trim(u.Path, "/")}, nil
+	case "", "file":
+		return dirBodyStore(filepath.FromSlash(u.Path)), nil
+	}
+	return nil, fmt.Errorf("unsupported body store %q, expected s3://, gs:// or a directory", rawURL)
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+type dirBodyStore string
+
+func (d dirBodyStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	compressed, err := gzipBody(body)
+	if err != nil {
+		return err
+	}
+	local := filepath.Join(string(d), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(local, compressed, 0644)
+}
+
+// Ping checks the directory exists, or can be made, and takes new files
+func (d dirBodyStore) Ping(ctx context.Context) error {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(string(d), ".ping.*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+type objectBodyStore struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func (s *objectBodyStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	compressed, err := gzipBody(body)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, path.Join(s.prefix, key), bytes.NewReader(compressed), int64(len(compressed)), minio.PutObjectOptions{
+		ContentType:     contentType,
+		ContentEncoding: "gzip",
+	})
+	return err
+}
+
+func (s *objectBodyStore) Ping(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err == nil && !exists {
+		err = fmt.Errorf("no bucket %s", s.bucket)
+	}
+	return err
+}
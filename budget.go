@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BudgetRule caps how many of the URLs whose path and query match Pattern are fetched, so sections like tag pages
+// can't use up the crawl
+type BudgetRule struct {
+	Pattern *regexp.Regexp
+	Limit   int
+}
+
+// budgetCount is how a crawl has gone against one of its Budgets
+type budgetCount struct {
+	fetched, skipped int
+}
+
+// parseBudgetRules parses rules given as pattern=limit, like /tag/=500, the pattern being a regexp and the limit split
+// off at the last =
+func parseBudgetRules(specs []string) ([]BudgetRule, error) {
+	var rules []BudgetRule
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("budget rule %s isn't pattern=limit", spec)
+		}
+		pattern, err := regexp.Compile(spec[:i])
+		if err != nil {
+			return nil, fmt.Errorf("budget rule %s: %w", spec, err)
+		}
+		limit, err := strconv.Atoi(spec[i+1:])
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("budget rule %s has a limit that isn't a count", spec)
+		}
+		rules = append(rules, BudgetRule{Pattern: pattern, Limit: limit})
+	}
+	return rules, nil
+}
+
+// withinBudget reports whether u may be fetched under the first of Budgets it matches, counting it against that rule
+// either way
+func (c *Crawler) withinBudget(u *url.URL) bool {
+	target := u.RequestURI()
+	for i, rule := range c.Budgets {
+		if !rule.Pattern.MatchString(target) {
+			continue
+		}
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		if c.budgets == nil {
+			c.budgets = make([]budgetCount, len(c.Budgets))
+		}
+		if c.budgets[i].fetched >= rule.Limit {
+			c.budgets[i].skipped++
+			return false
+		}
+		c.budgets[i].fetched++
+		return true
+	}
+	return true
+}
+
+// logOverBudget reports the URLs each of the crawler's Budgets, and its Sample, left unfetched
+func logOverBudget(c *Crawler) {
+	if skipped := c.unpicked.Load(); skipped > 0 {
+		log.Infof("Skipped %d URLs outside the %g sample", skipped, c.Sample)
+	}
+	for i, skipped := range c.OverBudget() {
+		if skipped > 0 {
+			log.Infof("Skipped %d URLs matching %s over its budget of %d", skipped, c.Budgets[i].Pattern, c.Budgets[i].Limit)
+		}
+	}
+}
+
+// OverBudget returns how many URLs were left unfetched for each of Budgets, in order
+func (c *Crawler) OverBudget() []int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	skipped := make([]int, len(c.Budgets))
+	for i, count := range c.budgets {
+		skipped[i] = count.skipped
+	}
+	return skipped
+}
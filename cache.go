@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCachedBody is the largest body redisCache keeps, so one huge download can't fill Redis
+const maxCachedBody = 10 << 20
+
+// cachePrefix is what redisCache's keys start with, before the URL
+const cachePrefix = "monzo:cache:"
+
+// redisCache is a RoundTripper keeping responses to GETs in Redis for ttl, so crawls sharing it, like short lived
+// containers or one restarted, get them from there instead of the site again
+type redisCache struct {
+	next   http.RoundTripper
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// cachedResponse is a response as redisCache keeps it
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+func newRedisCache(next http.RoundTripper, redisURL string, ttl time.Duration) (*redisCache, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisCache{next: next, client: client, ttl: ttl}, nil
+}
+
+func (c *redisCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != "" { //what's fetched with credentials or a session isn't for crawls without them
+		return c.next.RoundTrip(req)
+	}
+	key := cachePrefix + req.URL.String()
+	data, err := c.client.Get(req.Context(), key).Bytes()
+	if err == nil {
+		var cached cachedResponse
+		if err := json.Unmarshal(data, &cached); err == nil {
+			log.Debug("Cached", req.URL.String())
+			return cached.response(req), nil
+		}
+	} else if err != redis.Nil {
+		log.Warning("couldn't read the cache, fetching instead:", err)
+	}
+	resp, err := c.next.RoundTrip(req)
+	if err != nil || !cacheable(resp) {
+		return resp, err
+	}
+	resp.Body = &cachingBody{ReadCloser: resp.Body, cache: c, key: key, resp: resp}
+	return resp, nil
+}
+
+// cacheable reports whether resp may be kept: a whole, successful or redirecting response the server didn't forbid
+// keeping
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode > 399 || resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	control := strings.ToLower(resp.Header.Get("Cache-Control"))
+	return !strings.Contains(control, "no-store") && !strings.Contains(control, "private")
+}
+
+func (r *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// cachingBody copies a body as it's read, keeping the response in the cache once it has all been. Bodies closed
+// early, like those of statics, aren't kept.
+type cachingBody struct {
+	io.ReadCloser
+	cache *redisCache
+	key   string
+	resp  *http.Response
+	data  bytes.Buffer
+	large bool //outgrew maxCachedBody, so isn't being copied
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.large {
+		b.data.Write(p[:n])
+		b.large = b.data.Len() > maxCachedBody
+	}
+	if err == io.EOF && !b.large {
+		b.store()
+		b.large = true //so it's only kept once
+	}
+	return n, err
+}
+
+func (b *cachingBody) store() {
+	header := b.resp.Header.Clone()
+	header.Del("Set-Cookie") //which were meant for the crawl fetching it
+	data, err := json.Marshal(cachedResponse{Status: b.resp.StatusCode, Header: header, Body: b.data.Bytes()})
+	if err != nil {
+		return
+	}
+	if err := b.cache.client.Set(context.Background(), b.key, data, b.cache.ttl).Err(); err != nil {
+		log.Warning("couldn't cache", b.resp.Request.URL.String()+":", err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("hello")), Request: req}, nil
+}
+
+// A request carrying a session cookie goes straight to the site, without the cache being read or its response kept
+func TestRedisCacheBypassesCookies(t *testing.T) {
+	next := &countingTransport{}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}) //nothing listens there, so any use of the cache would fail or warn
+	defer client.Close()
+	cache := &redisCache{next: next, client: client}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("Cookie", "session=secret")
+	resp, err := cache.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.requests != 1 {
+		t.Errorf("expected the request to go to the site once, went %d times", next.requests)
+	}
+	if _, ok := resp.Body.(*cachingBody); ok {
+		t.Error("expected the response not to be kept in the cache")
+	}
+}
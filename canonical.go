@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// indexPages are the file names servers commonly serve for a directory, so /dir/index.html is the same page as /dir/
+var indexPages = []string{"index.html", "index.htm", "index.php", "default.aspx", "default.asp"}
+
+// trackingParams are query parameters that don't change a page's content, just who gets credit for the visit
+var trackingParams = []string{"utm_", "fbclid", "gclid", "msclkid", "mc_cid", "mc_eid"}
+
+func contentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
+
+// claimContent records body as the content of target, reporting false if an earlier page had the same, in which case
+// target is recorded as an alias of that page and linked to it instead of being parsed
+func (c *Crawler) claimContent(target *Page, body []byte) bool {
+	(*target).Size, (*target).ContentHash = int64(len(body)), contentHash(body)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	original, ok := c.contents[(*target).ContentHash]
+	if !ok {
+		c.contents[(*target).ContentHash] = target
+		return true
+	}
+	(*original).Aliases = append((*original).Aliases, (*target).URL.String())
+	(*target).Links = append((*target).Links, original)
+	log.Debugf("%s has the same content as %s, not parsing it again", (*target).URL.String(), (*original).URL.String())
+	return false
+}
+
+// parseCanonical records a <link rel="canonical">
+func parseCanonical(token html.Token, target *Page) {
+	if !strings.EqualFold(strings.TrimSpace(attrValue(token, "rel")), "canonical") {
+		return
+	}
+	relURL, err := url.Parse(strings.TrimSpace(attrValue(token, "href")))
+	if err != nil || relURL.String() == "" {
+		return
+	}
+	u := (*target).resolve(relURL)
+	u.Fragment = ""
+	(*target).Canonical = u.String()
+}
+
+// normaliseURL reduces a URL to a key that its trivially different variants share: case, trailing slashes,
+// index pages, query parameter order and tracking parameters
+func normaliseURL(u *url.URL) string {
+	n := *u
+	n.Scheme, n.Host = strings.ToLower(n.Scheme), strings.ToLower(n.Host)
+	n.Path = strings.ToLower(n.Path)
+	for _, index := range indexPages {
+		if strings.HasSuffix(n.Path, "/"+index) {
+			n.Path = strings.TrimSuffix(n.Path, index)
+			break
+		}
+	}
+	n.Path = strings.TrimRight(n.Path, "/")
+	query := n.Query()
+	for param := range query {
+		for _, tracking := range trackingParams {
+			if strings.HasPrefix(strings.ToLower(param), tracking) {
+				query.Del(param)
+			}
+		}
+	}
+	n.RawQuery = query.Encode() //which sorts by key
+	n.Fragment, n.RawPath, n.ForceQuery = "", "", false
+	return n.String()
+}
+
+// writeDuplicateAudit groups pages that are the same page under different URLs, because they have identical content,
+// normalise to the same URL or name one another as canonical, recommending one canonical URL for each group
+func writeDuplicateAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	var pages []*Page
+	for _, page := range sitePages(root) {
+		if page.isHTML() && page.Status >= 200 && page.Status <= 299 {
+			pages = append(pages, page)
+		}
+	}
+	parent := make(map[*Page]*Page, len(pages)) //union-find over pages, so clusters join up through any of the three
+	var find func(*Page) *Page
+	find = func(page *Page) *Page {
+		if parent[page] != page {
+			parent[page] = find(parent[page])
+		}
+		return parent[page]
+	}
+	byKey := make(map[string]*Page)
+	union := func(page *Page, key string) {
+		if other, ok := byKey[key]; ok {
+			parent[find(page)] = find(other)
+		} else {
+			byKey[key] = page
+		}
+	}
+	for _, page := range pages {
+		parent[page] = page
+	}
+	for _, page := range pages {
+		if page.ContentHash != "" {
+			union(page, "hash:"+page.ContentHash)
+		}
+		union(page, "url:"+normaliseURL(page.URL))
+		if page.Canonical != "" {
+			if canonical, err := url.Parse(page.Canonical); err == nil {
+				union(page, "url:"+normaliseURL(canonical))
+			}
+		}
+	}
+	clusters := make(map[*Page][]*Page)
+	var roots []*Page
+	for _, page := range pages {
+		root := find(page)
+		if _, ok := clusters[root]; !ok {
+			roots = append(roots, root)
+		}
+		clusters[root] = append(clusters[root], page)
+	}
+	for _, root := range roots {
+		cluster := clusters[root]
+		if len(cluster) < 2 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%d URLs, canonical %s\n", len(cluster), recommendCanonical(cluster)); err != nil {
+			return err
+		}
+		for _, page := range cluster {
+			line := "    " + displayURL(page.URL.String())
+			if page.Canonical != "" {
+				line += " (declares " + page.Canonical + ")"
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recommendCanonical picks the URL most of a cluster declares as canonical, or failing that its simplest URL
+func recommendCanonical(cluster []*Page) string {
+	votes := make(map[string]int)
+	for _, page := range cluster {
+		if page.Canonical != "" {
+			votes[page.Canonical]++
+		}
+	}
+	best, bestVotes := "", 0
+	for canonical, n := range votes {
+		if n > bestVotes || (n == bestVotes && canonical < best) {
+			best, bestVotes = canonical, n
+		}
+	}
+	if best != "" {
+		return best
+	}
+	urls := make([]string, len(cluster))
+	for i, page := range cluster {
+		urls[i] = page.URL.String()
+	}
+	sort.Slice(urls, func(i, j int) bool { //no query beats a query, then shorter, then lower case, then alphabetical
+		qi, qj := strings.Contains(urls[i], "?"), strings.Contains(urls[j], "?")
+		if qi != qj {
+			return !qi
+		}
+		if len(urls[i]) != len(urls[j]) {
+			return len(urls[i]) < len(urls[j])
+		}
+		li, lj := urls[i] == strings.ToLower(urls[i]), urls[j] == strings.ToLower(urls[j])
+		if li != lj {
+			return li
+		}
+		return urls[i] < urls[j]
+	})
+	return urls[0]
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runCheck is the check command, crawling for broken links and failing if it finds any, for CI
+func runCheck(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: monzo check [flags] [seed...]")
+		flags.PrintDefaults()
+	}
+	var seedOpts seedFlags
+	var fetch fetchFlags
+	var internal, github bool
+	seedOpts.register(flags)
+	fetch.register(flags)
+	flags.BoolVar(&internal, "internal", false, "Only check links within the crawl's scope, not those out of it")
+	flags.BoolVar(&github, "github", false, "Write the broken links as GitHub Actions error annotations, so they show on the workflow run and its pull request")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	seeds, err := seedOpts.seeds(flags)
+	if err != nil {
+		return fmt.Errorf("couldn't read seeds: %w", err)
+	}
+	configure, err := fetch.configure("")
+	if err != nil {
+		return err
+	}
+	crawler := NewCrawler(seeds, fetch.depth, seedOpts.scope)
+	configure(crawler)
+	seedOpts.limit(crawler)
+	crawler.CheckExternal = !internal
+	pages := crawler.Run(context.Background())
+	write := writeBrokenLinks
+	if github {
+		write = writeAnnotations
+	}
+	broken, err := write(os.Stdout, pages)
+	if err != nil {
+		return err
+	}
+	log.Infof("Crawled %d URLs, %d of them broken", crawler.Seen(), broken)
+	logOverBudget(crawler)
+	if broken > 0 {
+		return fmt.Errorf("found %d broken links", broken)
+	}
+	return nil
+}
+
+// brokenLinks finds every link that couldn't be fetched, or came back with an error status, returning how each went and
+// the pages linking to it by URL. A broken seed has no pages linking to it.
+func brokenLinks(roots []*Page) (outcomes map[string]string, sources map[string][]string) {
+	outcomes, sources = make(map[string]string), make(map[string][]string)
+	for _, root := range roots {
+		if root.broken() {
+			outcomes[root.URL.String()] = root.outcome()
+		}
+	}
+	for _, page := range graphPages(roots) {
+		for _, link := range page.Links {
+			if link.broken() {
+				outcomes[link.URL.String()] = link.outcome()
+				sources[link.URL.String()] = append(sources[link.URL.String()], page.URL.String())
+			}
+		}
+		for _, asset := range page.External {
+			if asset.broken() {
+				outcome := fmt.Sprint(asset.Status)
+				if asset.Error != "" {
+					outcome = "error: " + asset.Error
+				}
+				outcomes[asset.URL] = outcome
+				sources[asset.URL] = append(sources[asset.URL], page.URL.String())
+			}
+		}
+	}
+	return outcomes, sources
+}
+
+// writeBrokenLinks lists the brokenLinks with the pages linking to each, returning how many there were
+func writeBrokenLinks(w io.Writer, roots []*Page) (int, error) {
+	outcomes, sources := brokenLinks(roots)
+	broken := make([]string, 0, len(outcomes))
+	for rawURL := range outcomes {
+		broken = append(broken, rawURL)
+	}
+	sort.Strings(broken)
+	colour := colourful(w)
+	for _, rawURL := range broken {
+		outcome := outcomes[rawURL]
+		if colour {
+			outcome = paint(red, outcome)
+		}
+		if _, err := fmt.Fprintf(w, "%s (%s)\n", displayURL(rawURL), outcome); err != nil {
+			return 0, err
+		}
+		for _, source := range sources[rawURL] {
+			if _, err := fmt.Fprintln(w, "    from "+displayURL(source)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(broken), nil
+}
+
+// writeGitHub writes the github format, an annotation for each broken link under root, for CI to surface
+func writeGitHub(w io.Writer, root *Page) error {
+	_, err := writeAnnotations(w, []*Page{root})
+	return err
+}
+
+// writeAnnotations writes an error annotation for each of the brokenLinks in GitHub Actions' workflow command format,
+// naming the pages linking to it, returning how many there were
+func writeAnnotations(w io.Writer, roots []*Page) (int, error) {
+	outcomes, sources := brokenLinks(roots)
+	broken := make([]string, 0, len(outcomes))
+	for rawURL := range outcomes {
+		broken = append(broken, rawURL)
+	}
+	sort.Strings(broken)
+	for _, rawURL := range broken {
+		message := displayURL(rawURL) + " is broken (" + outcomes[rawURL] + ")"
+		if len(sources[rawURL]) > 0 {
+			from := make([]string, len(sources[rawURL]))
+			for i, source := range sources[rawURL] {
+				from[i] = displayURL(source)
+			}
+			message += ", linked from " + strings.Join(from, ", ")
+		}
+		title := "Broken link to " + displayURL(rawURL)
+		if _, err := fmt.Fprintf(w, "::error title=%s::%s\n", escapeProperty(title), escapeData(message)); err != nil {
+			return 0, err
+		}
+	}
+	return len(broken), nil
+}
+
+// escapeData escapes a workflow command's message, which would otherwise end at a newline
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command's property, which would otherwise end at a comma and split at a colon
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
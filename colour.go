@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// noColour turns off colouring terminal output, set by -no-color or NO_COLOR
+var noColour = os.Getenv("NO_COLOR") != ""
+
+const (
+	red     = "31"
+	green   = "32"
+	yellow  = "33"
+	blue    = "34"
+	magenta = "35"
+	cyan    = "36"
+	dim     = "2"
+)
+
+// depthColours colour each level of the indented webmap in turn, so siblings line up by eye
+var depthColours = []string{cyan, blue, magenta, yellow}
+
+// colourful reports whether w is a terminal worth colouring output for
+func colourful(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if noColour || !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func paint(colour, s string) string {
+	return "\x1b[" + colour + "m" + s + "\x1b[0m"
+}
+
+// paintStatus colours how fetching a page went by its class: green for success, yellow for redirects and red for the broken
+func paintStatus(page *Page) string {
+	switch {
+	case page.broken():
+		return paint(red, page.outcome())
+	case page.Status >= 300:
+		return paint(yellow, strconv.Itoa(page.Status))
+	case page.Status > 0:
+		return paint(green, strconv.Itoa(page.Status))
+	}
+	return paint(dim, "not fetched")
+}
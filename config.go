@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// envPrefix starts the environment variables that stand in for flags, MONZO_MAX_PAGES for -max-pages and so on
+const envPrefix = "MONZO_"
+
+// parseFlags parses a command's flags, adding -config and the logging flags, and sets up logging. Flags missing from
+// the command line come from their environment variable if it is set, then from the config file, then their defaults.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	var configPath string
+	var logOpts logFlags
+	logOpts.register(flags)
+	flags.StringVar(&configPath, "config", os.Getenv(envPrefix+"CONFIG"), "Read flags from this YAML or TOML file, keyed by flag name, under any given on the command line or in "+envPrefix+"* environment variables")
+	flags.Parse(args)
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	var config map[string]any
+	if configPath != "" {
+		var err error
+		if config, err = loadConfig(configPath); err != nil {
+			return fmt.Errorf("couldn't read config: %w", err)
+		}
+	}
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || f.Name == "config" {
+			return
+		}
+		if value, ok := os.LookupEnv(envVar(f.Name)); ok {
+			if setErr := flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("bad %s: %w", envVar(f.Name), setErr)
+			}
+			return
+		}
+		value, ok := config[f.Name]
+		if !ok {
+			return
+		}
+		values, isList := value.([]any) //for flags that can be given more than once
+		if !isList {
+			values = []any{value}
+		}
+		for _, value := range values {
+			if setErr := flags.Set(f.Name, fmt.Sprint(value)); setErr != nil {
+				err = fmt.Errorf("bad %s in %s: %w", f.Name, configPath, setErr)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	var unknown []string
+	for name := range config {
+		if flags.Lookup(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown { //perhaps for another command sharing the file
+		log.Warningf("%s: %s isn't a flag of monzo %s, ignoring it", configPath, name, flags.Name())
+	}
+	return nil
+}
+
+// envVar is the environment variable for a flag
+func envVar(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadConfig reads a config file, as TOML if it ends in .toml and YAML otherwise
+func loadConfig(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]any)
+	if filepath.Ext(path) == ".toml" {
+		err = toml.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
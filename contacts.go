@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d ().-]{6,}\d`)
+	datePattern  = regexp.MustCompile(`^\d{4}[-./]\d{1,2}[-./]\d{1,2}$|^\d{1,2}[-./]\d{1,2}[-./]\d{4}$`) //look like phone numbers otherwise
+)
+
+// findContacts records the email addresses and phone numbers in some of a page's visible text
+func findContacts(text string, target *Page) {
+	for _, email := range emailPattern.FindAllString(text, -1) {
+		addEmail(target, email)
+	}
+	for _, phone := range phonePattern.FindAllString(text, -1) {
+		if !datePattern.MatchString(phone) {
+			addPhone(target, phone)
+		}
+	}
+}
+
+// findContactLink records the target of a mailto: or tel: link
+func findContactLink(href string, target *Page) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "mailto":
+		for _, email := range strings.Split(u.Opaque, ",") { //mailto:a@b.com,c@d.com?subject=hi
+			if email, err := url.PathUnescape(email); err == nil && emailPattern.MatchString(email) {
+				addEmail(target, email)
+			}
+		}
+	case "tel":
+		addPhone(target, u.Opaque)
+	}
+}
+
+func addEmail(target *Page, email string) {
+	(*target).Emails = appendUnique((*target).Emails, strings.ToLower(strings.TrimRight(email, ".")))
+}
+
+// addPhone records a number in a normalised form, digits with any leading +, skipping runs of digits too short or long to be one
+func addPhone(target *Page, phone string) {
+	var normalised strings.Builder
+	digits := 0
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			normalised.WriteRune(r)
+			digits++
+		case r == '+' && i == 0:
+			normalised.WriteRune(r)
+		}
+	}
+	if digits < 7 || digits > 15 { //the shortest real numbers, and the longest E.164 allows
+		return
+	}
+	(*target).Phones = appendUnique((*target).Phones, normalised.String())
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// writeContacts writes the contacts format, every email address and phone number found under the page
+// with the pages each was found on
+func writeContacts(w io.Writer, page *Page) error {
+	emails, phones := make(map[string][]string), make(map[string][]string)
+	seen := make(map[*Page]struct{})
+	var walk func(*Page)
+	walk = func(page *Page) {
+		if _, ok := seen[page]; ok {
+			return
+		}
+		seen[page] = struct{}{}
+		for _, email := range page.Emails {
+			emails[email] = append(emails[email], page.URL.String())
+		}
+		for _, phone := range page.Phones {
+			phones[phone] = append(phones[phone], page.URL.String())
+		}
+		for _, link := range page.Links {
+			walk(link)
+		}
+	}
+	walk(page)
+	for _, section := range []struct {
+		name  string
+		found map[string][]string
+	}{{"Emails", emails}, {"Phones", phones}} {
+		if len(section.found) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s:\n", section.name); err != nil {
+			return err
+		}
+		contacts := make([]string, 0, len(section.found))
+		for contact := range section.found {
+			contacts = append(contacts, contact)
+		}
+		sort.Strings(contacts)
+		for _, contact := range contacts {
+			if _, err := fmt.Fprintf(w, "    %s\n", contact); err != nil {
+				return err
+			}
+			for _, found := range section.found[contact] {
+				if _, err := fmt.Fprintf(w, "        %s\n", found); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
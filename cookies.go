@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadCookies preloads a jar from a cookie file, either the Netscape cookies.txt format
+// or the JSON array that browser cookie export extensions write
+func loadCookies(jar *cookiejar.Jar, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cookies []*http.Cookie
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		cookies, err = parseJSONCookies(trimmed)
+	} else {
+		cookies, err = parseNetscapeCookies(data)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't parse cookie file %s: %v", path, err)
+	}
+	for _, cookie := range cookies {
+		host := strings.TrimPrefix(cookie.Domain, ".")
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{cookie})
+	}
+	log.Infof("loaded %d cookies from %s", len(cookies), path)
+	return nil
+}
+
+// parseNetscapeCookies reads tab separated lines of domain, include subdomains, path, secure, expiry, name and value
+func parseNetscapeCookies(data []byte) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d has %d fields, expected 7", line, len(fields))
+		}
+		cookie := &http.Cookie{
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		domain := fields[0]
+		if strings.EqualFold(fields[1], "TRUE") { //only a Domain attribute lets the cookie reach subdomains
+			cookie.Domain = domain
+		} else {
+			cookie.Domain = strings.TrimPrefix(domain, ".")
+		}
+		if expiry, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, scanner.Err()
+}
+
+func parseJSONCookies(data []byte) ([]*http.Cookie, error) {
+	var exported []struct {
+		Name           string  `json:"name"`
+		Value          string  `json:"value"`
+		Domain         string  `json:"domain"`
+		Path           string  `json:"path"`
+		Secure         bool    `json:"secure"`
+		HTTPOnly       bool    `json:"httpOnly"`
+		HostOnly       bool    `json:"hostOnly"`
+		ExpirationDate float64 `json:"expirationDate"`
+		Expires        float64 `json:"expires"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, err
+	}
+	cookies := make([]*http.Cookie, 0, len(exported))
+	for _, e := range exported {
+		cookie := &http.Cookie{Name: e.Name, Value: e.Value, Path: e.Path, Secure: e.Secure, HttpOnly: e.HTTPOnly}
+		if cookie.Path == "" {
+			cookie.Path = "/"
+		}
+		if e.HostOnly {
+			cookie.Domain = strings.TrimPrefix(e.Domain, ".")
+		} else {
+			cookie.Domain = e.Domain
+		}
+		expiry := e.ExpirationDate
+		if expiry == 0 {
+			expiry = e.Expires
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(int64(expiry), 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
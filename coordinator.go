@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/jackkleeman/monzo/crawlerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"hash/fnv"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// how often the coordinator reports progress, how long it waits before retrying a busy worker, and how long it gives a
+// worker it's giving up on to take the cancellation of its crawl
+const (
+	coordinatorProgressInterval = 5 * time.Second
+	coordinatorBusyRetry        = 2 * time.Second
+	coordinatorCancelTimeout    = 5 * time.Second
+)
+
+// errNoWorkers is returned once every worker has failed
+var errNoWorkers = errors.New("no workers left")
+
+// workerNode is a crawler process serving the gRPC API that the coordinator hands hosts to
+type workerNode struct {
+	addr   string
+	conn   *grpc.ClientConn
+	client crawlerpb.CrawlerClient
+	failed bool
+}
+
+// hostCrawl tracks one seed's host through however many workers it takes to crawl it
+type hostCrawl struct {
+	seed     *url.URL
+	worker   string
+	attempts int
+	done     bool
+	results  []*crawlerpb.PageResult
+}
+
+// Coordinator partitions seed hosts across workers, following their streamed results
+// and reassigning a host to another worker if its current one fails
+type Coordinator struct {
+	Depth             int
+	Scope             string
+	FoldTrailingSlash bool //sent with every crawl, so workers spell URLs as the coordinator spells their seeds
+
+	mutex   sync.Mutex
+	workers []*workerNode
+	hosts   []*hostCrawl
+}
+
+func NewCoordinator(addrs []string, depth int, scope string) (*Coordinator, error) {
+	c := &Coordinator{Depth: depth, Scope: scope}
+	for _, addr := range addrs {
+		conn, err := grpc.NewClient(addr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 10 * time.Second, Timeout: 5 * time.Second, PermitWithoutStream: true}), //notice dead workers mid-stream
+		)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.workers = append(c.workers, &workerNode{addr: addr, conn: conn, client: crawlerpb.NewCrawlerClient(conn)})
+	}
+	return c, nil
+}
+
+func (c *Coordinator) Close() {
+	for _, worker := range c.workers {
+		worker.conn.Close()
+	}
+}
+
+// assign picks the live worker responsible for a host, so the same host always lands on the same worker while it stays up
+func (c *Coordinator) assign(host string) *workerNode {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var alive []*workerNode
+	for _, worker := range c.workers {
+		if !worker.failed {
+			alive = append(alive, worker)
+		}
+	}
+	if len(alive) == 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(host))
+	return alive[int(h.Sum32()%uint32(len(alive)))]
+}
+
+func (c *Coordinator) fail(worker *workerNode, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !worker.failed {
+		log.Warningf("worker %s failed, reassigning its hosts: %v", worker.addr, err)
+		worker.failed = true
+	}
+}
+
+// Run crawls every seed on the workers, returning one rebuilt webmap per seed in the same order
+func (c *Coordinator) Run(ctx context.Context, seeds []*url.URL) ([]*Page, error) {
+	for _, seed := range seeds {
+		c.hosts = append(c.hosts, &hostCrawl{seed: spellURL(seed, c.FoldTrailingSlash)}) //as the worker normalises it, results and all
+	}
+	progressCtx, stopProgress := context.WithCancel(ctx)
+	defer stopProgress()
+	go c.reportProgress(progressCtx)
+	var wg sync.WaitGroup
+	errs := make(chan error, len(c.hosts))
+	for _, host := range c.hosts {
+		wg.Add(1)
+		go func(host *hostCrawl) {
+			defer wg.Done()
+			if err := c.crawlHost(ctx, host); err != nil {
+				errs <- err
+			}
+		}(host)
+	}
+	wg.Wait()
+	close(errs)
+	var firstErr error
+	for err := range errs {
+		log.Errorf("host crawl failed: %v", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	pages := make([]*Page, len(c.hosts))
+	for i, host := range c.hosts {
+		pages[i] = rebuildWebmap(host.seed, host.results)
+	}
+	return pages, firstErr
+}
+
+// crawlHost keeps handing a host to workers until one of them streams the whole crawl back
+func (c *Coordinator) crawlHost(ctx context.Context, host *hostCrawl) error {
+	for {
+		worker := c.assign(host.seed.Host)
+		if worker == nil {
+			return errNoWorkers
+		}
+		c.mutex.Lock()
+		host.worker = worker.addr
+		host.attempts++
+		host.results = nil //a reassigned host starts again from scratch
+		c.mutex.Unlock()
+		err := c.crawlOn(ctx, worker, host)
+		if err == nil {
+			c.mutex.Lock()
+			host.done = true
+			c.mutex.Unlock()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch status.Code(err) {
+		case codes.ResourceExhausted: //the worker's queue is full, it's busy rather than broken
+			select {
+			case <-time.After(coordinatorBusyRetry):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case codes.InvalidArgument:
+			return err
+		default:
+			c.fail(worker, err)
+		}
+	}
+}
+
+// crawlOn crawls host on worker, collecting its results. Once the crawl is submitted, any failure cancels it on the
+// worker, best effort, so a worker that's still alive doesn't go on crawling a host that could be given to another.
+func (c *Coordinator) crawlOn(ctx context.Context, worker *workerNode, host *hostCrawl) (err error) {
+	submitted, err := worker.client.SubmitCrawl(ctx, &crawlerpb.SubmitCrawlRequest{Seed: host.seed.String(), Depth: int32(c.Depth), Scope: c.Scope, FoldTrailingSlash: &c.FoldTrailingSlash})
+	if err != nil {
+		return err
+	}
+	log.Infof("crawling %s on worker %s as job %s", host.seed.Host, worker.addr, submitted.Id)
+	defer func() {
+		if err != nil {
+			cancelCtx, cancel := context.WithTimeout(context.Background(), coordinatorCancelTimeout)
+			defer cancel()
+			worker.client.CancelCrawl(cancelCtx, &crawlerpb.CancelCrawlRequest{Id: submitted.Id})
+		}
+	}()
+	stream, err := worker.client.StreamResults(ctx, &crawlerpb.StreamResultsRequest{Id: submitted.Id})
+	if err != nil {
+		return err
+	}
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		c.mutex.Lock()
+		host.results = append(host.results, result)
+		c.mutex.Unlock()
+	}
+}
+
+func (c *Coordinator) reportProgress(ctx context.Context) {
+	ticker := time.NewTicker(coordinatorProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.mutex.Lock()
+		done, pages, alive := 0, 0, 0
+		for _, host := range c.hosts {
+			if host.done {
+				done++
+			}
+			pages += len(host.results)
+		}
+		for _, worker := range c.workers {
+			if !worker.failed {
+				alive++
+			}
+		}
+		c.mutex.Unlock()
+		log.Infof("%d/%d hosts done, %d pages fetched, %d/%d workers alive", done, len(c.hosts), pages, alive, len(c.workers))
+	}
+}
+
+// rebuildWebmap turns a worker's flat page results back into the tree the worker's crawler built. seed needs spelling
+// as the worker normalised it, which its results are under.
+func rebuildWebmap(seed *url.URL, results []*crawlerpb.PageResult) *Page {
+	pages := map[string]*Page{seed.String(): {URL: seed}}
+	get := func(rawURL string) *Page {
+		page, ok := pages[rawURL]
+		if !ok {
+			parsed, err := url.Parse(rawURL)
+			if err != nil {
+				parsed = &url.URL{Path: rawURL}
+			}
+			page = &Page{URL: parsed}
+			pages[rawURL] = page
+		}
+		return page
+	}
+	for _, result := range results {
+		page := get(result.Url)
+		page.Title, page.Description, page.Keywords = result.Title, result.Description, result.Keywords
+		for _, static := range result.Statics {
+			if parsed, err := url.Parse(static); err == nil {
+				page.Statics = append(page.Statics, parsed)
+			}
+		}
+		for _, link := range result.Links {
+			page.Links = append(page.Links, get(link))
+		}
+	}
+	return pages[seed.String()]
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// A seed without a path comes back from the worker normalised to /, and the coordinator has to find it under that
+func TestCoordinatorSeedWithoutPath(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+		case "/a":
+			fmt.Fprint(w, `<html><body>a</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := NewJobManager(1, JobSettings{Configure: func(*Crawler) {}, Depth: 2})
+	go serveGRPC(listener, jobs)
+	coordinator, err := NewCoordinator([]string{listener.Addr().String()}, 2, ScopeHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coordinator.Close()
+	seed, _ := url.Parse(site.URL) //like http://127.0.0.1:1234, with no path
+	roots, err := coordinator.Run(context.Background(), []*url.URL{seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || len(roots[0].Links) != 1 {
+		t.Fatalf("expected the root with its one link, got %+v", roots)
+	}
+	if got, want := roots[0].Links[0].URL.String(), site.URL+"/a"; got != want {
+		t.Errorf("root links to %s, want %s", got, want)
+	}
+}
+
+// Workers spell URLs as the coordinator asks them to, whatever their own -fold-trailing-slash, so it finds the seed
+func TestCoordinatorTrailingSlashOverridesWorkers(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs", "/docs/":
+			fmt.Fprint(w, `<html><body><a href="/docs/a">a</a></body></html>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<html><body>a</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folding := func(c *Crawler) { c.FoldTrailingSlash = true } //as a worker started with -fold-trailing-slash
+	jobs := NewJobManager(1, JobSettings{Configure: folding, Depth: 2})
+	go serveGRPC(listener, jobs)
+	coordinator, err := NewCoordinator([]string{listener.Addr().String()}, 2, ScopeHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coordinator.Close()
+	seed, _ := url.Parse(site.URL + "/docs/")
+	roots, err := coordinator.Run(context.Background(), []*url.URL{seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || len(roots[0].Links) != 1 {
+		t.Fatalf("expected the root with its one link, got %+v", roots)
+	}
+	if got, want := roots[0].URL.String(), site.URL+"/docs/"; got != want {
+		t.Errorf("root is %s, want %s", got, want)
+	}
+}
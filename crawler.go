@@ -0,0 +1,1044 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Page struct {
+	URL                 *url.URL
+	Status              int               //HTTP status of the fetch, 0 if the page was never fetched or the request failed
+	Fetched             time.Time         //when the response arrived
+	Latency             time.Duration     //from sending the request to the response arriving, including any retries
+	Depth               int               //links from a seed the crawl found the page at, which may be more than ClickDepth
+	Referrer            string            //URL of the page the crawl first found this one on, empty for seeds
+	Fallback            string            //the URL under the other of http and https that answered when this one failed, if SchemeFallback is set
+	ContentType         string            //of the response
+	Redirects           []Redirect        //redirects followed to get to the page, if any
+	SecurityHeaders     map[string]string //the securityHeaders the response had, by name
+	Size                int64             //bytes of the body, after any decompression, or Content-Length for pages that weren't parsed
+	ContentHash         string            //of the body, for finding duplicate pages
+	Truncated           bool              //only the first MaxHTMLSize bytes of the HTML were read, so its links may be incomplete
+	BodyKey             string            //where the body was kept in the crawler's Bodies, if set
+	Fingerprint         string            //of the title and text without volatile words like dates, for noticing changes between crawls
+	Changed             *bool             //whether Fingerprint differs from the crawler's Previous one, if that was set
+	PageRank            float64           //share of the crawl's PageRank, if it was computed
+	Hub                 float64           //HITS score for linking to good authorities, if it was computed
+	Authority           float64           //HITS score for being linked to by good hubs, if it was computed
+	ClickDepth          int               //fewest links from a seed to the page, once the crawl is done
+	Structure           *SiteStats        //of the site under the page, set on each root of the webmap once the crawl is done
+	Partial             bool              //set on each root of the webmap if the crawl stopped before fetching everything it found
+	Canonical           string            //from <link rel="canonical">
+	Sitemap             []string          //URLs the site's sitemap lists, for seeds if the crawler's Sitemaps is set
+	Error               string            //why the fetch failed, if it did
+	TLSError            string            //why the server's certificate didn't verify, recorded even when verification is skipped
+	TLS                 *TLSInfo          //the connection and certificates of the page's host, shared by every page on it
+	UserAgent           string            //what the page was fetched as, if not Go's default
+	Title               string            //contents of the <title> tag
+	Description         string            //content of the description meta tag
+	Keywords            []string          //content of the keywords meta tag, split on commas
+	Robots              []string          //directives from the robots meta tag and X-Robots-Tag header, such as noindex
+	H1s                 []string          //text of each <h1>
+	Lang                string            //the html tag's lang attribute
+	DetectedLang        string            //ISO 639 code of the language the visible text is in, if it could be told
+	StructuredData      []any             //JSON-LD blocks and microdata items on the page, decoded like JSON
+	Fields              map[string]any    //values the crawler's Scrape rules extracted, by name
+	Text                string            //visible text of the page, only kept if the crawler's KeepText is set
+	MainText            string            //the page's main content without navigation and the like, only kept if the crawler's MainText is set
+	WordCount           int               //words in MainText
+	Relevance           float64           //share of the crawler's Focus keywords on the page, if it was set
+	Matches             []GrepMatch       //lines matching the crawler's Grep
+	Emails              []string          //addresses in the page's text and mailto: links, if the crawler's Contacts is set
+	Phones              []string          //numbers in the page's text and tel: links, digits only but for a leading +
+	Statics             []*url.URL
+	MixedContent        []string   //http:// subresources of an https page, such as images, scripts, stylesheets and iframes
+	AccessibilityIssues []string   //problems found if the crawler's Accessibility is set, like images without alt text
+	Assets              []*Asset   //what checking each of Statics found, if the crawler's CheckStatics is set
+	ExternalLinks       []*url.URL //links out of the crawl's scope, listed but not followed
+	External            []*Asset   //what checking each of ExternalLinks found, if the crawler's CheckExternal is set
+	Links               []*Page
+	Aliases             []string     //other URLs serving the same content, crawled as this page when the crawler's DedupContent is set
+	EmptyLinks          int          //distinct hrefs that were blank or only a fragment, so lead nowhere new
+	Anchors             []*Anchor    //every <a href> on the page, in order, whether or not it was followed
+	Alternates          []*Alternate //the page's hreflang alternates, from <link rel="alternate" hreflang>
+
+	base     *url.URL  //from <base href>, what the page's relative URLs resolve against instead of its own
+	used     *url.URL  //Fallback, which the page's relative URLs resolve against if it has no base
+	exchange *exchange //the request and response as they went, if the crawler's KeepExchanges is set
+}
+
+// Redirect is one hop of the redirects followed to fetch a page
+type Redirect struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int    `json:"status"`
+}
+
+// redirectChain lists the redirects that led to resp, in the order they were followed
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		chain = append(chain, Redirect{From: req.Response.Request.URL.String(), To: req.URL.String(), Status: req.Response.StatusCode})
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// securityHeaders are the response headers recorded for the security header audit
+var securityHeaders = []string{
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+	"Referrer-Policy",
+}
+
+// Alternate is a version of a page in another language or region
+type Alternate struct {
+	Lang string `json:"lang"` //as given by hreflang, such as en-GB or x-default
+	URL  string `json:"url"`
+}
+
+// Anchor is a link as it appears on a page, for anchor text analysis
+type Anchor struct {
+	URL  string   `json:"url"`
+	Text string   `json:"text,omitempty"` //visible text of the link, including the alt text of images in it
+	Rel  []string `json:"rel,omitempty"`
+
+	labelled bool //has an aria-label or title, which stand in for text
+}
+
+// scopes decide which discovered links are followed, relative to the seed
+const (
+	ScopeHost   = "host"   //only the seed's host
+	ScopeDomain = "domain" //the seed's host and any of its subdomains
+	ScopePrefix = "prefix" //the seed's host, under the seed's path
+)
+
+func validScope(scope string) bool {
+	switch scope {
+	case ScopeHost, ScopeDomain, ScopePrefix:
+		return true
+	}
+	return false
+}
+
+// DefaultConcurrency is how many pages a crawler fetches at once unless told otherwise
+const DefaultConcurrency = 64
+
+// Crawler holds the state of a single crawl, so that several can exist in one process
+type Crawler struct {
+	Seeds             []*url.URL //every seed shares the one seen-set, and links within scope of any of them are followed
+	Depth             int
+	Scope             string
+	FoldTrailingSlash bool              //treat /docs/ and /docs as the same URL
+	DedupContent      bool              //treat URLs serving identical HTML as one page, following only the first one's links
+	SchemeFallback    bool              //fetch pages that fail under http or https under the other instead
+	Concurrency       int               //number of workers fetching pages at once
+	HostConcurrency   int               //most pages to fetch at once from any one origin, 0 for no limit beyond Concurrency
+	Delay             time.Duration     //least time between starting fetches from any one origin
+	IgnoreCrawlDelay  bool              //pace by Delay alone, even where robots.txt asks for a longer Crawl-delay
+	Adaptive          bool              //narrow each origin's concurrency while it struggles, widening it again as it recovers
+	Retries           int               //how many more times to try fetching a page after network errors, 429s and 5xxs
+	BodyTimeout       time.Duration     //longest reading an HTML body may take before it is abandoned and retried, 0 for no limit
+	MinRate           int64             //least bytes a second an HTML body may arrive at once it has had minRateGrace, 0 for no minimum
+	MaxHTMLSize       int64             //most bytes of an HTML body to read, links past them going unfound, 0 for no limit
+	Client            *http.Client      //what pages are fetched with
+	Credentials       Credentials       //auth for requests within scope
+	UserAgents        *UserAgents       //user agents to rotate between, nil for Go's default
+	Onion             bool              //follow links to .onion hosts, when Client goes through Tor
+	TLSRoots          *x509.CertPool    //what certificates are checked against when the client skips verification, nil for the system roots
+	CertExpiryWarning time.Duration     //warn about certificates expiring within this, 0 for DefaultCertExpiryWarning
+	Frontier          Frontier          //where URLs wait to be fetched, in memory unless the crawl is shared
+	Priority          PriorityFunc      //scores each discovered URL for StrategyPriority, nil for urlPriority
+	KeepText          bool              //record each page's visible text, for sinks that index it
+	KeepExchanges     bool              //record each page's request, response headers and body, for WARC
+	MainText          bool              //extract each page's main content, which means parsing it a second time
+	Markdown          string            //if set, save each page's main content as Markdown under this directory
+	PDFLinks          bool              //follow the link annotations in PDFs too
+	Focus             *Focus            //if set, only follow links from seeds and pages relevant to it
+	Grep              *regexp.Regexp    //if set, record the lines of each page's text that match
+	GrepHTML          bool              //match Grep against the raw HTML instead of the text
+	Scrape            []*ScrapeRule     //fields to extract from every page
+	Contacts          bool              //collect email addresses and phone numbers from every page
+	CheckStatics      bool              //HEAD every static to find broken and oversized ones
+	CheckExternal     bool              //HEAD every link out of scope to find broken ones
+	Accessibility     bool              //check every page for basic accessibility problems
+	Sitemaps          bool              //fetch each seed's sitemap
+	Mirror            *Mirror           //if set, save every fetched page to disk
+	StaticStore       *StaticStore      //if set, download every static into it
+	Archive           *Archive          //if set, add every fetched body to it
+	Bodies            BodyStore         //if set, keep every fetched body in it
+	Previous          map[string]string //fingerprints from an earlier crawl by URL, to mark pages Changed against
+	Prior             map[string]*Page  //pages from an earlier crawl by URL, for Revisit to reuse
+	Validators        *Validators       //if set, what pages were fetched with before, to fetch them again only if modified
+	Revisit           []RevisitRule     //how often URLs need fetching again, the first rule a URL matches reusing its Prior page until then
+	MaxPages          int               //stop fetching once this many pages have been fetched, 0 for no limit
+	Sample            float64           //share of the URLs found, besides seeds, to fetch, picked uniformly, 0 for all of them
+	Budgets           []BudgetRule      //most URLs to fetch matching each pattern, the first rule a URL matches counting
+	Deadline          time.Time         //stop starting fetches once this passes, letting those in flight finish, zero for none
+
+	OnPage func(*Page) //if set, called with each fetched page once it has been fully parsed
+
+	fetched  atomic.Int64 //pages fetched, or about to be, counted against MaxPages
+	cut      atomic.Bool  //whether MaxPages or Deadline left pages unfetched
+	unpicked atomic.Int64 //URLs left unfetched outside Sample
+	mutex    sync.Mutex
+	pages    map[string]*Page         //every page this process has created, so popped URLs get the Page their referrer linked to
+	popped   map[string]struct{}      //URLs this process has taken from the frontier
+	detached []*Page                  //pages popped by this process that another process discovered
+	hosts    map[string]chan struct{} //a semaphore per origin, when HostConcurrency is set
+	paced    map[string]time.Time     //when each origin may next be fetched from, when Delay is set
+	limits   map[string]*hostThrottle //how many fetches each origin is coping with, when Adaptive is set
+	robots   map[string]*robotsInfo   //what each origin's robots.txt asks for
+	budgets  []budgetCount            //against each of Budgets, once one is matched
+	contents map[string]*Page         //the first page with each ContentHash, when DedupContent is set
+	assets   map[string]*Asset        //statics and external links checked so far, when CheckStatics or CheckExternal is set
+	tlsHosts map[string]*TLSInfo      //the TLS each host was first fetched over
+	fellBack sync.Map                 //origins that only answered under the other scheme, when SchemeFallback is set
+}
+
+func NewCrawler(seeds []*url.URL, depth int, scope string) *Crawler {
+	if scope == "" {
+		scope = ScopeHost
+	}
+	return &Crawler{
+		Seeds:       seeds,
+		Depth:       depth,
+		Scope:       scope,
+		Concurrency: DefaultConcurrency,
+		Client:      http.DefaultClient,
+		Frontier:    newMemoryFrontier(StrategyBFS),
+		pages:       make(map[string]*Page),
+		popped:      make(map[string]struct{}),
+		hosts:       make(map[string]chan struct{}),
+		paced:       make(map[string]time.Time),
+		limits:      make(map[string]*hostThrottle),
+		robots:      make(map[string]*robotsInfo),
+		contents:    make(map[string]*Page),
+		assets:      make(map[string]*Asset),
+		tlsHosts:    make(map[string]*TLSInfo),
+	}
+}
+
+// Run crawls from the seeds and blocks until the frontier is exhausted, returning the top level Page of each seed in order.
+// Once ctx is done no more pages are fetched, so the returned Pages are only as complete as the crawl got.
+func (c *Crawler) Run(ctx context.Context) []*Page {
+	var targets []*Page
+	c.normaliseSeeds()
+	for _, seed := range c.Seeds {
+		if _, ok := c.pages[seed.String()]; ok { //a seed listed twice only gets crawled, and output, once
+			continue
+		}
+		target := &Page{URL: seed} //create top level Page
+		c.pages[seed.String()] = target
+		targets = append(targets, target)
+		if _, err := c.Frontier.Push(ctx, FrontierItem{URL: seed.String(), Depth: c.Depth}); err != nil {
+			log.Errorf("failed to queue seed %s: %v", seed.String(), err)
+			return targets
+		}
+	}
+	if c.Sitemaps { //before any workers start, so seed pages are complete by the time they are handed on
+		for _, target := range targets {
+			sitemap := defaultSitemap(target.URL)
+			urls, err := c.fetchSitemap(ctx, sitemap)
+			if err != nil {
+				log.Warningf("couldn't fetch sitemap %s: %v", sitemap, err)
+				continue
+			}
+			target.Sitemap = append([]string{}, urls...) //not nil, even if empty, to tell it apart from no sitemap
+		}
+	}
+	var wg sync.WaitGroup //this waits for every worker to run out of work
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.work(ctx)
+		}()
+	}
+	wg.Wait()
+	if c.Validators != nil {
+		if err := c.Validators.Save(); err != nil {
+			log.Errorf("failed to save validators: %v", err)
+		}
+	}
+	for _, target := range targets {
+		target.Partial = c.cut.Load()
+	}
+	return targets
+}
+
+// Detached returns pages this process fetched for a shared crawl that were discovered by another process,
+// each the root of its own part of the webmap
+func (c *Crawler) Detached() []*Page {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.detached
+}
+
+// Seen returns the number of unique URLs this crawl has come across
+func (c *Crawler) Seen() int {
+	seen, err := c.Frontier.Seen(context.Background())
+	if err != nil {
+		log.Errorf("failed to count seen URLs: %v", err)
+	}
+	return seen
+}
+
+func (c *Crawler) work(ctx context.Context) {
+	for {
+		item, err := c.Frontier.Pop(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("failed to take a URL from the frontier: %v", err)
+			}
+			return
+		}
+		if item == nil { //the crawl has finished
+			return
+		}
+		c.crawlPage(ctx, c.page(item), item.Depth)
+		if err := c.Frontier.Done(context.WithoutCancel(ctx), *item); err != nil {
+			log.Errorf("failed to mark %s as done: %v", item.URL, err)
+		}
+	}
+}
+
+// page finds the Page for a popped URL, creating a detached one if this process didn't discover it
+func (c *Crawler) page(item *FrontierItem) *Page {
+	rawURL := item.URL
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.popped[rawURL] = struct{}{}
+	if page, ok := c.pages[rawURL]; ok {
+		return page
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil { //only URLs we have serialised ourselves reach the frontier
+		parsed = &url.URL{Path: rawURL}
+	}
+	page := &Page{URL: parsed, Referrer: item.Referrer}
+	c.pages[rawURL] = page
+	c.detached = append(c.detached, page)
+	return page
+}
+
+// claimRedirect marks the URL a page was redirected to as seen, so it isn't fetched and parsed again under that URL.
+// It reports false if that URL had been seen already, linking the page to the one for it if this process has that.
+func (c *Crawler) claimRedirect(ctx context.Context, target *Page, final *url.URL) bool {
+	finalURL := c.normalise(final)
+	finalURL.Fragment = ""
+	key := finalURL.String()
+	if key == (*target).URL.String() {
+		return true
+	}
+	added, err := c.Frontier.MarkSeen(ctx, key)
+	if err != nil {
+		log.Errorf("failed to mark %s as seen: %v", key, err)
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	existing, ok := c.pages[key]
+	if added { //links to it found from now on are to this page
+		if !ok {
+			c.pages[key] = target
+		}
+		return true
+	}
+	if ok {
+		(*target).Links = append((*target).Links, existing)
+	}
+	log.Debugf("%s redirects to %s, which is crawled already", (*target).URL.String(), key)
+	return false
+}
+
+// tlsInfo returns what the crawl recorded of host's TLS, recording it from state and warning about any problems if this is the first time
+func (c *Crawler) tlsInfo(host string, state *tls.ConnectionState) *TLSInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if info, ok := c.tlsHosts[host]; ok {
+		return info
+	}
+	info := newTLSInfo(host, state)
+	c.tlsHosts[host] = info
+	expiry := c.CertExpiryWarning
+	if expiry == 0 {
+		expiry = DefaultCertExpiryWarning
+	}
+	for _, warning := range info.warnings(time.Now(), expiry) {
+		log.Warningf("TLS on %s: %s", host, warning)
+	}
+	return info
+}
+
+// saveBody hands a fetched page's body to whichever of the mirror, archive and body store are set, and keeps it with
+// the page's exchange if it has one
+func (c *Crawler) saveBody(ctx context.Context, target *Page, body []byte) {
+	if (*target).exchange != nil {
+		(*target).exchange.body = body
+	}
+	if c.Bodies != nil {
+		key := bodyKey((*target).URL)
+		if err := c.Bodies.Put(ctx, key, (*target).ContentType, body); err != nil {
+			log.Errorf("failed to store body of %s: %v", (*target).URL.String(), err)
+		} else {
+			(*target).BodyKey = key
+		}
+	}
+	if c.Mirror != nil {
+		c.Mirror.savePage(target, body)
+	}
+	if c.Archive != nil {
+		c.Archive.savePage(target, body)
+	}
+}
+
+// acquireHost waits for a free slot to fetch from u's origin under HostConcurrency and any Adaptive throttle, then for
+// Delay, or any longer Crawl-delay in its robots.txt, to pass since the last fetch from it started, returning false if ctx
+// ends first
+func (c *Crawler) acquireHost(ctx context.Context, u *url.URL) (release func(), ok bool) {
+	release = func() {}
+	if c.HostConcurrency > 0 {
+		slots := c.hostSlots(u)
+		select {
+		case slots <- struct{}{}:
+			release = func() { <-slots }
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+	if c.Adaptive {
+		throttle := c.throttle(u)
+		if !throttle.acquire(ctx) {
+			release()
+			return nil, false
+		}
+		releaseSlot := release
+		release = func() {
+			throttle.release()
+			releaseSlot()
+		}
+	}
+	delay := max(c.Delay, c.crawlDelay(ctx, u))
+	if delay <= 0 {
+		return release, true
+	}
+	origin := u.Scheme + "://" + u.Host
+	c.mutex.Lock()
+	next := c.paced[origin]
+	if now := time.Now(); next.Before(now) {
+		next = now
+	}
+	c.paced[origin] = next.Add(delay)
+	c.mutex.Unlock()
+	if !c.Deadline.IsZero() && next.After(c.Deadline) { //its turn would only come after the crawl has stopped
+		c.cut.Store(true)
+		release()
+		return nil, false
+	}
+	select {
+	case <-time.After(time.Until(next)):
+		return release, true
+	case <-ctx.Done():
+		release()
+		return nil, false
+	}
+}
+
+// hostSlots returns the semaphore limiting fetches from u's origin
+func (c *Crawler) hostSlots(u *url.URL) chan struct{} {
+	origin := u.Scheme + "://" + u.Host
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	slots, ok := c.hosts[origin]
+	if !ok {
+		slots = make(chan struct{}, c.HostConcurrency)
+		c.hosts[origin] = slots
+	}
+	return slots
+}
+
+func (c *Crawler) inScope(u *url.URL) bool {
+	if isOnion(u) && !c.Onion { //without Tor these can't be reached, and looking them up leaks them
+		return false
+	}
+	for _, seed := range c.Seeds {
+		if inSeedScope(c.Scope, seed, u) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOnion(u *url.URL) bool {
+	return strings.HasSuffix(strings.ToLower(u.Hostname()), ".onion")
+}
+
+func inSeedScope(scope string, seed, u *url.URL) bool {
+	switch scope {
+	case ScopeDomain:
+		return u.Host == seed.Host || strings.HasSuffix(u.Host, "."+seed.Host)
+	case ScopePrefix:
+		return u.Host == seed.Host && strings.HasPrefix(u.Path, seed.Path)
+	}
+	return u.Host == seed.Host
+}
+
+// newRequest builds a request for the crawl, with its credentials if the URL is within scope
+func (c *Crawler) newRequest(ctx context.Context, method, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.inScope(req.URL) {
+		c.Credentials.apply(req)
+	}
+	if agent := c.UserAgents.pick(req.URL.Host); agent != "" {
+		req.Header.Set("User-Agent", agent)
+	}
+	return req, nil
+}
+
+func (c *Crawler) pageDone(target *Page) {
+	if c.OnPage != nil {
+		c.OnPage(target)
+	}
+}
+
+func (c *Crawler) crawlPage(ctx context.Context, target *Page, depth int) error {
+	if depth <= 0 || ctx.Err() != nil { //reached our max depth, or the crawl was cancelled
+		return nil
+	}
+	if prior := c.fresh((*target).URL); prior != nil { //fetched recently enough last time
+		c.reuse(ctx, target, prior, depth)
+		return nil
+	}
+	if depth != c.Depth && !c.sampled((*target).URL) { //recorded as linked to, but not fetched
+		c.unpicked.Add(1)
+		return nil
+	}
+	if !c.withinBudget((*target).URL) { //left unfetched, like pages past MaxPages, but counted for its rule
+		return nil
+	}
+	if c.MaxPages > 0 && c.fetched.Add(1) > int64(c.MaxPages) || !c.Deadline.IsZero() && time.Now().After(c.Deadline) {
+		c.cut.Store(true) //out of budget, the rest of the frontier drains unfetched
+		return nil
+	}
+	defer c.pageDone(target)
+	req, err := c.newRequest(ctx, "GET", (*target).URL.String())
+	if err != nil {
+		log.Errorf("failed to build request for URL %s: %v", (*target).URL.String(), err)
+		return err
+	}
+	var known *validator
+	if c.Validators != nil {
+		known = c.Validators.condition(req)
+	}
+	if c.CheckExternal {
+		defer c.checkExternal(ctx, target)
+	}
+	if c.CheckStatics { //deferred before the host is acquired so it runs once that is released, as assets may share the host
+		defer c.checkAssets(ctx, target)
+	}
+	if c.Mirror != nil && c.Mirror.Statics {
+		defer c.Mirror.saveStatics(ctx, c, target)
+	}
+	if c.StaticStore != nil {
+		defer c.StaticStore.download(ctx, c, target)
+	}
+	release, ok := c.acquireHost(ctx, req.URL)
+	if !ok {
+		return nil
+	}
+	defer release() //held until the whole body has been read
+	(*target).UserAgent = req.Header.Get("User-Agent")
+	(*target).Depth = c.Depth - depth
+	start := time.Now()
+	resp, err := c.doFallback(ctx, target, req)
+	(*target).Latency = time.Since(start)
+	if err != nil {
+		log.Errorf("failed to get URL %s: %v", (*target).URL.String(), err)
+		(*target).Error = err.Error()
+		if isTLSVerificationError(err) {
+			(*target).TLSError = err.Error()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if c.Validators != nil {
+		defer c.Validators.remember(target, resp.Header) //once its links have been found
+	}
+	(*target).Status = resp.StatusCode
+	(*target).Fetched = time.Now().UTC()
+	log.Debugf("fetched %s: %d", (*target).URL.String(), resp.StatusCode)
+	(*target).ContentType = resp.Header.Get("Content-Type")
+	(*target).Redirects = redirectChain(resp)
+	if (len((*target).Redirects) > 0 || (*target).used != nil) && !c.claimRedirect(ctx, target, resp.Request.URL) {
+		return nil //what it redirects or fell back to is crawled under its own URL
+	}
+	if c.KeepExchanges {
+		(*target).exchange = newExchange(resp)
+	}
+	for _, name := range securityHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if (*target).SecurityHeaders == nil {
+				(*target).SecurityHeaders = make(map[string]string)
+			}
+			(*target).SecurityHeaders[name] = value
+		}
+	}
+	for _, robots := range resp.Header.Values("X-Robots-Tag") {
+		addRobots(target, robots)
+	}
+	if err := verifyPeer(resp.TLS, resp.Request.URL.Hostname(), c.TLSRoots); err != nil {
+		(*target).TLSError = err.Error()
+	}
+	if resp.TLS != nil {
+		(*target).TLS = c.tlsInfo(resp.Request.URL.Host, resp.TLS)
+	}
+	if resp.StatusCode == http.StatusNotModified && known != nil {
+		c.notModified(ctx, target, known, depth)
+		return nil
+	}
+	if resp.StatusCode >= 400 { //recorded with its status, but an error page's links, often the whole navigation, aren't followed
+		(*target).Size = max(resp.ContentLength, 0)
+		return nil
+	}
+	if !(*target).isHTML() {
+		(*target).Size = max(resp.ContentLength, 0)
+		pdf := c.PDFLinks && isPDF((*target).ContentType)
+		if c.Mirror != nil || c.Archive != nil || c.Bodies != nil || c.KeepExchanges || pdf {
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				log.Errorf("failed to read body of URL %s: %v", (*target).URL.String(), err)
+				return err
+			}
+			c.saveBody(ctx, target, body)
+			if pdf {
+				if len(body) > maxPDFSize {
+					body = body[:maxPDFSize]
+				}
+				for _, link := range pdfLinks(body) {
+					c.parseLink(ctx, link, target, depth)
+				}
+			}
+		}
+		return nil
+	}
+	seenRefs := make(map[string]struct{}) //this will ensure we dont repeat the same statics and links within a given page
+	var title, text []string              //words of the title and of the visible text
+	inTitle, hidden := false, 0           //whether we're in the title, and how many script or style tags deep
+	var body bytes.Buffer                 //kept for building a tree of the page, for structured data, scraping and main text
+	structured := false
+	var anchor *Anchor //the <a> tag we're in, if any, collecting its text
+	var h1 []string    //words of the <h1> we're in, nil if we aren't in one
+	var held []string  //hrefs waiting on the page's relevance to Focus, which needs all of its text
+	var accessibility *accessibilityChecks
+	if c.Accessibility {
+		accessibility = newAccessibilityChecks()
+	}
+	var content io.Reader = resp.Body
+	if c.MaxHTMLSize > 0 {
+		content = io.LimitReader(resp.Body, c.MaxHTMLSize)
+	}
+	if c.DedupContent { //all of the body is needed to tell whether it is a copy, before any of its links are followed
+		data, err := io.ReadAll(content)
+		if err != nil {
+			log.Errorf("failed to read body of URL %s: %v", (*target).URL.String(), err)
+			return err
+		}
+		if !c.claimContent(target, data) {
+			return nil
+		}
+		content = bytes.NewReader(data)
+	}
+	tokens := html.NewTokenizer(io.TeeReader(content, &body))
+	line := 1 //of the HTML, that the current token starts on
+	for {
+		tokenType := tokens.Next()
+		tokenLine := line
+		line += bytes.Count(tokens.Raw(), []byte("\n"))
+		if tokenType == html.ErrorToken { //an EOF
+			if c.MaxHTMLSize > 0 && int64(body.Len()) == c.MaxHTMLSize {
+				var next [1]byte
+				n, _ := io.ReadFull(resp.Body, next[:]) //there's more if it has another byte
+				(*target).Truncated = n > 0
+			}
+			(*target).Title = strings.Join(title, " ")
+			(*target).Size = int64(body.Len())
+			(*target).ContentHash = contentHash(body.Bytes())
+			(*target).Fingerprint = fingerprint(title, text)
+			if c.Previous != nil {
+				changed := c.Previous[(*target).URL.String()] != (*target).Fingerprint
+				(*target).Changed = &changed
+			}
+			if c.Focus != nil {
+				(*target).Relevance = c.Focus.relevance(title, text)
+				if depth == c.Depth || (*target).Relevance >= c.Focus.Threshold { //seeds are followed whatever they're about
+					for _, href := range held {
+						c.parseLink(ctx, href, target, depth)
+					}
+				} else {
+					log.Debugf("not following the links on %s, only %.2f relevant", (*target).URL.String(), (*target).Relevance)
+				}
+			}
+			c.saveBody(ctx, target, body.Bytes())
+			if c.KeepText {
+				(*target).Text = strings.Join(text, " ")
+			}
+			(*target).DetectedLang = detectLanguage(text)
+			if accessibility != nil {
+				accessibility.finish(target)
+			}
+			if c.Grep != nil && c.GrepHTML {
+				(*target).Matches = grepLines(c.Grep, body.String(), 1)
+			}
+			if structured || c.Scrape != nil { //both need the page as a tree
+				doc, err := html.Parse(bytes.NewReader(body.Bytes()))
+				if err != nil {
+					log.Errorf("failed to parse page %s: %v", (*target).URL.String(), err)
+					return err
+				}
+				if structured {
+					(*target).StructuredData = parseStructuredData(doc, (*target).URL)
+				}
+				if c.Scrape != nil {
+					(*target).Fields = scrape(doc, c.Scrape)
+				}
+			}
+			if c.MainText {
+				mainText, err := extractMainText(body.Bytes(), (*target).URL)
+				if err != nil {
+					log.Warningf("couldn't find the main text of %s: %v", (*target).URL.String(), err)
+				}
+				(*target).MainText, (*target).WordCount = mainText, len(strings.Fields(mainText))
+			}
+			if c.Markdown != "" && (*target).Status == http.StatusOK {
+				if err := saveMarkdown(c.Markdown, target, body.Bytes()); err != nil {
+					log.Warningf("couldn't save %s as Markdown: %v", (*target).URL.String(), err)
+				}
+			}
+			return nil
+		}
+		token := tokens.Token()
+		if accessibility != nil {
+			accessibility.token(tokenType, token)
+		}
+		if tokenType == html.StartTagToken || tokenType == html.SelfClosingTagToken {
+			switch token.DataAtom {
+			case atom.Meta:
+				parseMeta(token, target)
+			case atom.Link:
+				parseAlternate(token, target)
+				parseCanonical(token, target)
+			case atom.Base:
+				parseBase(token, target)
+			case atom.A:
+				if opened := newAnchor(token, target); tokenType == html.StartTagToken { //a self-closed <a/> has no text to collect
+					anchor = opened
+				}
+				if c.Contacts {
+					findContactLink(attrValue(token, "href"), target)
+				}
+			}
+			switch token.DataAtom.String() {
+			case "a", "link": //link tags
+				for _, attr := range token.Attr {
+					if attr.Key == "href" {
+						_, ok := seenRefs[attr.Val]
+						if !ok {
+							seenRefs[attr.Val] = struct{}{} //add this ref to list of those seen on this page
+							if c.Focus != nil {
+								held = append(held, attr.Val)
+							} else {
+								c.parseLink(ctx, attr.Val, target, depth)
+							}
+						}
+					}
+				}
+			case "img", "image", "script": //static tags
+				for _, attr := range token.Attr {
+					if attr.Key == "src" {
+						_, ok := seenRefs[attr.Val]
+						if !ok {
+							seenRefs[attr.Val] = struct{}{} //add this ref to list of those seen on this page
+							c.parseStatic(attr.Val, target)
+						}
+					}
+				}
+			}
+			structured = structured || hasStructuredData(token)
+			if (*target).URL.Scheme == "https" {
+				findMixedContent(token, target)
+			}
+			if anchor != nil && token.DataAtom == atom.Img {
+				anchor.addText(attrValue(token, "alt"))
+			}
+		}
+		switch tokenType {
+		case html.TextToken:
+			if inTitle {
+				title = append(title, strings.Fields(token.Data)...)
+			} else if hidden == 0 {
+				text = append(text, strings.Fields(token.Data)...)
+			}
+			if anchor != nil && hidden == 0 {
+				anchor.addText(token.Data)
+			}
+			if h1 != nil && hidden == 0 {
+				h1 = append(h1, strings.Fields(token.Data)...)
+			}
+			if c.Contacts && hidden == 0 {
+				findContacts(token.Data, target)
+			}
+			if c.Grep != nil && !c.GrepHTML && hidden == 0 {
+				(*target).Matches = append((*target).Matches, grepLines(c.Grep, token.Data, tokenLine)...)
+			}
+		case html.EndTagToken: //closing tag
+			switch token.DataAtom {
+			case atom.Title:
+				inTitle = false
+			case atom.A:
+				anchor = nil
+			case atom.H1:
+				if h1 != nil {
+					(*target).H1s = append((*target).H1s, strings.Join(h1, " "))
+					h1 = nil
+				}
+			case atom.Script, atom.Style:
+				if hidden > 0 { //ignore stray closing tags
+					hidden--
+				}
+			}
+		case html.StartTagToken: //opening tag
+			switch token.DataAtom {
+			case atom.Title:
+				inTitle = true
+			case atom.Script, atom.Style:
+				hidden++
+			case atom.Html:
+				(*target).Lang = attrValue(token, "lang")
+			case atom.H1:
+				h1 = []string{}
+			}
+		}
+	}
+}
+
+// newAnchor records an <a> tag on the page, returning nil if it has no usable href
+func newAnchor(token html.Token, target *Page) *Anchor {
+	if !hasAttr(token, "href") {
+		return nil
+	}
+	relURL, err := url.Parse(strings.TrimSpace(attrValue(token, "href")))
+	if err != nil {
+		return nil
+	}
+	u := (*target).resolve(relURL)
+	u.Fragment = ""
+	anchor := &Anchor{URL: u.String(), Rel: strings.Fields(strings.ToLower(attrValue(token, "rel"))), labelled: hasAttr(token, "aria-label") || hasAttr(token, "title")}
+	(*target).Anchors = append((*target).Anchors, anchor)
+	return anchor
+}
+
+// parseBase records the first <base href>, which the page's relative URLs after it resolve against
+func parseBase(token html.Token, target *Page) {
+	href := strings.TrimSpace(attrValue(token, "href"))
+	if (*target).base != nil || href == "" {
+		return
+	}
+	if relURL, err := url.Parse(href); err == nil {
+		(*target).base = (*target).resolve(relURL)
+	}
+}
+
+// resolve makes a URL found on the page absolute, against its <base href> if it had one. Protocol relative URLs like
+// //cdn.example.com/x.js take the page's scheme, or its Fallback's if that's what answered.
+func (p *Page) resolve(ref *url.URL) *url.URL {
+	if p.base != nil {
+		return p.base.ResolveReference(ref)
+	}
+	if p.used != nil {
+		return p.used.ResolveReference(ref)
+	}
+	return p.URL.ResolveReference(ref)
+}
+
+// parseAlternate records a <link> tag if it is an hreflang alternate
+func parseAlternate(token html.Token, target *Page) {
+	lang, href := attrValue(token, "hreflang"), attrValue(token, "href")
+	if lang == "" || href == "" {
+		return
+	}
+	isAlternate := false
+	for _, rel := range strings.Fields(attrValue(token, "rel")) {
+		isAlternate = isAlternate || strings.EqualFold(rel, "alternate")
+	}
+	relURL, err := url.Parse(href)
+	if !isAlternate || err != nil {
+		return
+	}
+	u := (*target).resolve(relURL)
+	u.Fragment = ""
+	(*target).Alternates = append((*target).Alternates, &Alternate{Lang: lang, URL: u.String()})
+}
+
+func (a *Anchor) addText(text string) {
+	if words := strings.Fields(text); len(words) > 0 {
+		a.Text = strings.TrimSpace(a.Text + " " + strings.Join(words, " "))
+	}
+}
+
+// findMixedContent records a tag's subresource if it would be loaded over plain http, for https pages
+func findMixedContent(token html.Token, target *Page) {
+	var ref string
+	switch token.DataAtom {
+	case atom.Img, atom.Image, atom.Script, atom.Iframe, atom.Audio, atom.Video, atom.Source, atom.Track, atom.Embed:
+		ref = attrValue(token, "src")
+	case atom.Link: //stylesheets, icons, preloads and the like, but not links to other pages
+		rel := strings.ToLower(attrValue(token, "rel"))
+		if !strings.Contains(rel, "alternate") && !strings.Contains(rel, "canonical") && !strings.Contains(rel, "next") && !strings.Contains(rel, "prev") {
+			ref = attrValue(token, "href")
+		}
+	case atom.Object:
+		ref = attrValue(token, "data")
+	}
+	relURL, err := url.Parse(strings.TrimSpace(ref))
+	if ref == "" || err != nil {
+		return
+	}
+	if u := (*target).resolve(relURL); u.Scheme == "http" {
+		(*target).MixedContent = appendUnique((*target).MixedContent, u.String())
+	}
+}
+
+// addRobots records the directives of a robots meta tag or X-Robots-Tag header, like noindex, nofollow
+func addRobots(target *Page, directives string) {
+	for _, directive := range strings.Split(directives, ",") {
+		if directive = strings.ToLower(strings.TrimSpace(directive)); directive != "" {
+			(*target).Robots = appendUnique((*target).Robots, directive)
+		}
+	}
+}
+
+// isHTML reports whether the page was fetched and is HTML, so was parsed
+func (p *Page) isHTML() bool {
+	if p.Fetched.IsZero() {
+		return false
+	}
+	return isHTMLType(p.ContentType)
+}
+
+func isHTMLType(contentType string) bool {
+	return contentType == "" || strings.HasPrefix(contentType, "text/html") // "" to allow for no header being sent
+}
+
+// parseMeta records the robots, description and keywords meta tags, keeping the first of each
+func parseMeta(token html.Token, target *Page) {
+	content := attrValue(token, "content")
+	switch strings.ToLower(attrValue(token, "name")) {
+	case "robots":
+		addRobots(target, content)
+	case "description":
+		if (*target).Description == "" {
+			(*target).Description = strings.Join(strings.Fields(content), " ")
+		}
+	case "keywords":
+		if (*target).Keywords == nil {
+			for _, keyword := range strings.Split(content, ",") {
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					(*target).Keywords = append((*target).Keywords, keyword)
+				}
+			}
+		}
+	}
+}
+
+func (c *Crawler) parseLink(ctx context.Context, href string, current *Page, depth int) error {
+	if href = strings.TrimSpace(href); href == "" || strings.HasPrefix(href, "#") { //would resolve to the current page
+		(*current).EmptyLinks++
+		return nil
+	}
+	relURL, err := url.Parse(href)
+	if err != nil {
+		log.Errorf("failed to parse URL %s on page %s: %v", href, (*current).URL.String(), err)
+		return err
+	}
+	newURL := (*current).resolve(relURL) //resolve the relative link to absolute
+	newURL = c.normalise(newURL)         //in the one spelling of it the crawl dedups on
+	if !c.inScope(newURL) {              //we are not interested in following links outside the crawl scope, only listing them
+		log.Debugf("not following %s from %s, out of scope", newURL.String(), (*current).URL.String())
+		if newURL.Scheme == "http" || newURL.Scheme == "https" {
+			newURL.Fragment = ""
+			for _, external := range (*current).ExternalLinks {
+				if external.String() == newURL.String() {
+					return nil
+				}
+			}
+			(*current).ExternalLinks = append((*current).ExternalLinks, newURL)
+		}
+		return nil
+	}
+	newURL.Fragment = "" //ignore fragments as they are irrelevant to crawling
+	newPage := &Page{URL: newURL, Referrer: (*current).URL.String()}
+	c.mutex.Lock()                                    //register the page before it can be popped, so whoever pops it finds this one
+	if existing, ok := c.pages[newURL.String()]; ok { //this process has seen this url before, so link to the page we already have
+		c.mutex.Unlock()
+		for _, link := range (*current).Links {
+			if link == existing { //already linked under another href
+				return nil
+			}
+		}
+		(*current).Links = append((*current).Links, existing)
+		return nil
+	}
+	c.pages[newURL.String()] = newPage
+	c.mutex.Unlock()
+	added, err := c.Frontier.Push(ctx, FrontierItem{URL: newURL.String(), Depth: depth - 1, Priority: c.priority(newURL, depth-1), Referrer: newPage.Referrer})
+	if err != nil {
+		log.Errorf("failed to queue URL %s: %v", newURL.String(), err)
+		return err
+	}
+	if !added { //another process has seen this url before, we note but do not follow
+		c.mutex.Lock()
+		if _, ok := c.popped[newURL.String()]; ok { //we got to fetching it anyway, so it stands on its own
+			c.detached = append(c.detached, newPage)
+		} else { //whoever pops it will make a detached page of their own
+			delete(c.pages, newURL.String())
+		}
+		c.mutex.Unlock()
+		return nil
+	}
+	(*current).Links = append((*current).Links, newPage)
+	return nil
+}
+
+func (c *Crawler) parseStatic(href string, current *Page) error {
+	relURL, err := url.Parse(strings.TrimSpace(href)) //so " //cdn.example.com/x.js" is protocol relative, not a path
+	if err != nil {
+		log.Errorf("failed to parse URL %s on page %s: %v", href, (*current).URL.String(), err)
+		return err
+	}
+	/*if relURL.Host != (*current).URL.Host { //we are not interested in external links
+		return nil
+	}*/
+	newURL := (*current).resolve(relURL) //resolve the link to absolute (ignores if it already was)
+	newURL.Fragment = ""                 //ignore fragments as they are irrelevant to crawling
+	(*current).Statics = append((*current).Statics, newURL)
+	return nil
+}
@@ -0,0 +1,461 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: crawlerpb/crawler.proto
+
+package crawlerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubmitCrawlRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Seed              string                 `protobuf:"bytes,1,opt,name=seed,proto3" json:"seed,omitempty"`
+	Depth             int32                  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+	Scope             string                 `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"`
+	MaxPages          int32                  `protobuf:"varint,4,opt,name=max_pages,json=maxPages,proto3" json:"max_pages,omitempty"`
+	Sink              string                 `protobuf:"bytes,5,opt,name=sink,proto3" json:"sink,omitempty"`
+	FoldTrailingSlash *bool                  `protobuf:"varint,6,opt,name=fold_trailing_slash,json=foldTrailingSlash,proto3,oneof" json:"fold_trailing_slash,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *SubmitCrawlRequest) Reset() {
+	*x = SubmitCrawlRequest{}
+	mi := &file_crawlerpb_crawler_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitCrawlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitCrawlRequest) ProtoMessage() {}
+
+func (x *SubmitCrawlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawlerpb_crawler_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitCrawlRequest.ProtoReflect.Descriptor instead.
+func (*SubmitCrawlRequest) Descriptor() ([]byte, []int) {
+	return file_crawlerpb_crawler_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubmitCrawlRequest) GetSeed() string {
+	if x != nil {
+		return x.Seed
+	}
+	return ""
+}
+
+func (x *SubmitCrawlRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *SubmitCrawlRequest) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *SubmitCrawlRequest) GetMaxPages() int32 {
+	if x != nil {
+		return x.MaxPages
+	}
+	return 0
+}
+
+func (x *SubmitCrawlRequest) GetSink() string {
+	if x != nil {
+		return x.Sink
+	}
+	return ""
+}
+
+func (x *SubmitCrawlRequest) GetFoldTrailingSlash() bool {
+	if x != nil && x.FoldTrailingSlash != nil {
+		return *x.FoldTrailingSlash
+	}
+	return false
+}
+
+type SubmitCrawlResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitCrawlResponse) Reset() {
+	*x = SubmitCrawlResponse{}
+	mi := &file_crawlerpb_crawler_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitCrawlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitCrawlResponse) ProtoMessage() {}
+
+func (x *SubmitCrawlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawlerpb_crawler_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitCrawlResponse.ProtoReflect.Descriptor instead.
+func (*SubmitCrawlResponse) Descriptor() ([]byte, []int) {
+	return file_crawlerpb_crawler_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SubmitCrawlResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type StreamResultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamResultsRequest) Reset() {
+	*x = StreamResultsRequest{}
+	mi := &file_crawlerpb_crawler_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamResultsRequest) ProtoMessage() {}
+
+func (x *StreamResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawlerpb_crawler_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamResultsRequest.ProtoReflect.Descriptor instead.
+func (*StreamResultsRequest) Descriptor() ([]byte, []int) {
+	return file_crawlerpb_crawler_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamResultsRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PageResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Statics       []string               `protobuf:"bytes,2,rep,name=statics,proto3" json:"statics,omitempty"`
+	Links         []string               `protobuf:"bytes,3,rep,name=links,proto3" json:"links,omitempty"`
+	Title         string                 `protobuf:"bytes,4,opt,name=title,proto3" json:"title,omitempty"`
+	Description   string                 `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	Keywords      []string               `protobuf:"bytes,6,rep,name=keywords,proto3" json:"keywords,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageResult) Reset() {
+	*x = PageResult{}
+	mi := &file_crawlerpb_crawler_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageResult) ProtoMessage() {}
+
+func (x *PageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_crawlerpb_crawler_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageResult.ProtoReflect.Descriptor instead.
+func (*PageResult) Descriptor() ([]byte, []int) {
+	return file_crawlerpb_crawler_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PageResult) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *PageResult) GetStatics() []string {
+	if x != nil {
+		return x.Statics
+	}
+	return nil
+}
+
+func (x *PageResult) GetLinks() []string {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+func (x *PageResult) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *PageResult) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *PageResult) GetKeywords() []string {
+	if x != nil {
+		return x.Keywords
+	}
+	return nil
+}
+
+type CancelCrawlRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelCrawlRequest) Reset() {
+	*x = CancelCrawlRequest{}
+	mi := &file_crawlerpb_crawler_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelCrawlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCrawlRequest) ProtoMessage() {}
+
+func (x *CancelCrawlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawlerpb_crawler_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCrawlRequest.ProtoReflect.Descriptor instead.
+func (*CancelCrawlRequest) Descriptor() ([]byte, []int) {
+	return file_crawlerpb_crawler_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CancelCrawlRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CancelCrawlResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelCrawlResponse) Reset() {
+	*x = CancelCrawlResponse{}
+	mi := &file_crawlerpb_crawler_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelCrawlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCrawlResponse) ProtoMessage() {}
+
+func (x *CancelCrawlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawlerpb_crawler_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCrawlResponse.ProtoReflect.Descriptor instead.
+func (*CancelCrawlResponse) Descriptor() ([]byte, []int) {
+	return file_crawlerpb_crawler_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancelCrawlResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+var File_crawlerpb_crawler_proto protoreflect.FileDescriptor
+
+const file_crawlerpb_crawler_proto_rawDesc = "" +
+	"\n" +
+	"\x17crawlerpb/crawler.proto\x12\rmonzo.crawler\"\xd2\x01\n" +
+	"\x12SubmitCrawlRequest\x12\x12\n" +
+	"\x04seed\x18\x01 \x01(\tR\x04seed\x12\x14\n" +
+	"\x05depth\x18\x02 \x01(\x05R\x05depth\x12\x14\n" +
+	"\x05scope\x18\x03 \x01(\tR\x05scope\x12\x1b\n" +
+	"\tmax_pages\x18\x04 \x01(\x05R\bmaxPages\x12\x12\n" +
+	"\x04sink\x18\x05 \x01(\tR\x04sink\x123\n" +
+	"\x13fold_trailing_slash\x18\x06 \x01(\bH\x00R\x11foldTrailingSlash\x88\x01\x01B\x16\n" +
+	"\x14_fold_trailing_slash\"%\n" +
+	"\x13SubmitCrawlResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"&\n" +
+	"\x14StreamResultsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xa2\x01\n" +
+	"\n" +
+	"PageResult\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x18\n" +
+	"\astatics\x18\x02 \x03(\tR\astatics\x12\x14\n" +
+	"\x05links\x18\x03 \x03(\tR\x05links\x12\x14\n" +
+	"\x05title\x18\x04 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x05 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bkeywords\x18\x06 \x03(\tR\bkeywords\"$\n" +
+	"\x12CancelCrawlRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"-\n" +
+	"\x13CancelCrawlResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status2\x88\x02\n" +
+	"\aCrawler\x12T\n" +
+	"\vSubmitCrawl\x12!.monzo.crawler.SubmitCrawlRequest\x1a\".monzo.crawler.SubmitCrawlResponse\x12Q\n" +
+	"\rStreamResults\x12#.monzo.crawler.StreamResultsRequest\x1a\x19.monzo.crawler.PageResult0\x01\x12T\n" +
+	"\vCancelCrawl\x12!.monzo.crawler.CancelCrawlRequest\x1a\".monzo.crawler.CancelCrawlResponseBE\n" +
+	"\x19me.jkleeman.monzo.crawlerP\x01Z&github.com/jackkleeman/monzo/crawlerpbb\x06proto3"
+
+var (
+	file_crawlerpb_crawler_proto_rawDescOnce sync.Once
+	file_crawlerpb_crawler_proto_rawDescData []byte
+)
+
+func file_crawlerpb_crawler_proto_rawDescGZIP() []byte {
+	file_crawlerpb_crawler_proto_rawDescOnce.Do(func() {
+		file_crawlerpb_crawler_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_crawlerpb_crawler_proto_rawDesc), len(file_crawlerpb_crawler_proto_rawDesc)))
+	})
+	return file_crawlerpb_crawler_proto_rawDescData
+}
+
+var file_crawlerpb_crawler_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_crawlerpb_crawler_proto_goTypes = []any{
+	(*SubmitCrawlRequest)(nil),   // 0: monzo.crawler.SubmitCrawlRequest
+	(*SubmitCrawlResponse)(nil),  // 1: monzo.crawler.SubmitCrawlResponse
+	(*StreamResultsRequest)(nil), // 2: monzo.crawler.StreamResultsRequest
+	(*PageResult)(nil),           // 3: monzo.crawler.PageResult
+	(*CancelCrawlRequest)(nil),   // 4: monzo.crawler.CancelCrawlRequest
+	(*CancelCrawlResponse)(nil),  // 5: monzo.crawler.CancelCrawlResponse
+}
+var file_crawlerpb_crawler_proto_depIdxs = []int32{
+	0, // 0: monzo.crawler.Crawler.SubmitCrawl:input_type -> monzo.crawler.SubmitCrawlRequest
+	2, // 1: monzo.crawler.Crawler.StreamResults:input_type -> monzo.crawler.StreamResultsRequest
+	4, // 2: monzo.crawler.Crawler.CancelCrawl:input_type -> monzo.crawler.CancelCrawlRequest
+	1, // 3: monzo.crawler.Crawler.SubmitCrawl:output_type -> monzo.crawler.SubmitCrawlResponse
+	3, // 4: monzo.crawler.Crawler.StreamResults:output_type -> monzo.crawler.PageResult
+	5, // 5: monzo.crawler.Crawler.CancelCrawl:output_type -> monzo.crawler.CancelCrawlResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_crawlerpb_crawler_proto_init() }
+func file_crawlerpb_crawler_proto_init() {
+	if File_crawlerpb_crawler_proto != nil {
+		return
+	}
+	file_crawlerpb_crawler_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_crawlerpb_crawler_proto_rawDesc), len(file_crawlerpb_crawler_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_crawlerpb_crawler_proto_goTypes,
+		DependencyIndexes: file_crawlerpb_crawler_proto_depIdxs,
+		MessageInfos:      file_crawlerpb_crawler_proto_msgTypes,
+	}.Build()
+	File_crawlerpb_crawler_proto = out.File
+	file_crawlerpb_crawler_proto_goTypes = nil
+	file_crawlerpb_crawler_proto_depIdxs = nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+// storedPage is a page as read back from a crawl written in the json or ndjson format, whose links are nested pages or URLs respectively
+type storedPage struct {
+	pageRecord
+	Links []json.RawMessage `json:"links"`
+}
+
+// restore fills in page from the record, but for its links
+func (r *pageRecord) restore(page *Page) {
+	page.Status, page.ContentType, page.Redirects, page.SecurityHeaders = r.Status, r.ContentType, r.Redirects, r.SecurityHeaders
+	page.Latency, page.Depth, page.Referrer, page.Fallback = time.Duration(r.LatencyMS*float64(time.Millisecond)), r.Depth, r.Referrer, r.Fallback
+	page.Size, page.ContentHash, page.BodyKey, page.Fingerprint, page.Changed = r.Size, r.ContentHash, r.BodyKey, r.Fingerprint, r.Changed
+	page.Truncated = r.Truncated
+	page.PageRank, page.Hub, page.Authority, page.ClickDepth, page.Structure = r.PageRank, r.Hub, r.Authority, r.ClickDepth, r.Structure
+	page.Canonical, page.Sitemap, page.Error, page.TLSError, page.TLS, page.UserAgent = r.Canonical, r.Sitemap, r.Error, r.TLSError, r.TLS, r.UserAgent
+	page.Title, page.Description, page.Keywords, page.Robots, page.H1s = r.Title, r.Description, r.Keywords, r.Robots, r.H1s
+	page.Lang, page.DetectedLang, page.StructuredData, page.Fields = r.Lang, r.DetectedLang, r.StructuredData, r.Fields
+	page.Text, page.MainText, page.WordCount, page.Matches, page.Emails, page.Phones = r.Text, r.MainText, r.WordCount, r.Matches, r.Emails, r.Phones
+	page.MixedContent, page.AccessibilityIssues, page.Assets, page.External = r.MixedContent, r.AccessibilityIssues, r.Assets, r.External
+	page.Anchors, page.Alternates, page.Partial, page.Relevance, page.EmptyLinks, page.Aliases = r.Anchors, r.Alternates, r.Partial, r.Relevance, r.EmptyLinks, r.Aliases
+	if r.Fetched != nil {
+		page.Fetched = *r.Fetched
+	}
+	page.Statics, page.ExternalLinks = parseURLs(r.Statics), parseURLs(r.ExternalLinks)
+}
+
+// parseURLs parses the URLs that still do, nil for none
+func parseURLs(rawURLs []string) []*url.URL {
+	var urls []*url.URL
+	for _, rawURL := range rawURLs {
+		if u, err := url.Parse(rawURL); err == nil {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// loadCrawl reads back a crawl written in the json or ndjson format, returning the roots of its webmaps and its pages by URL
+func loadCrawl(path string) ([]*Page, map[string]*Page, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	pages := make(map[string]*Page)
+	get := func(rawURL string) *Page {
+		page, ok := pages[rawURL]
+		if !ok {
+			parsed, err := url.Parse(rawURL)
+			if err != nil {
+				parsed = &url.URL{Path: rawURL}
+			}
+			page = &Page{URL: parsed}
+			pages[rawURL] = page
+		}
+		return page
+	}
+	var add func(stored storedPage) (*Page, error)
+	add = func(stored storedPage) (*Page, error) {
+		page := get(stored.URL)
+		if stored.Status == 0 && stored.Error == "" { //only linked to, or listed in full elsewhere
+			return page, nil
+		}
+		stored.restore(page)
+		for _, raw := range stored.Links {
+			var link string
+			if err := json.Unmarshal(raw, &link); err == nil {
+				page.Links = append(page.Links, get(link))
+				continue
+			}
+			var nested storedPage
+			if err := json.Unmarshal(raw, &nested); err != nil {
+				return nil, err
+			}
+			linked, err := add(nested)
+			if err != nil {
+				return nil, err
+			}
+			page.Links = append(page.Links, linked)
+		}
+		return page, nil
+	}
+	var tops []*Page
+	dec := json.NewDecoder(f)
+	for {
+		var stored storedPage
+		if err := dec.Decode(&stored); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("%s isn't a crawl in the json or ndjson format: %w", path, err)
+		}
+		top, err := add(stored)
+		if err != nil {
+			return nil, nil, err
+		}
+		tops = append(tops, top)
+	}
+	//ndjson has a record for every page, parents first, so only those not under an earlier one are roots
+	var roots []*Page
+	under := make(map[*Page]struct{})
+	for _, top := range tops {
+		if _, ok := under[top]; ok {
+			continue
+		}
+		roots = append(roots, top)
+		for _, page := range sitePages(top) {
+			under[page] = struct{}{}
+		}
+	}
+	return roots, pages, nil
+}
+
+// crawled reports whether a page was fetched, or tried, rather than just linked to
+func (p *Page) crawled() bool {
+	return p.Status != 0 || p.Error != ""
+}
+
+// broken reports whether a page was tried but couldn't be fetched successfully
+func (p *Page) broken() bool {
+	return p != nil && (p.Error != "" || p.Status >= 400)
+}
+
+// outcome is a page's status, or its error if it couldn't be fetched
+func (p *Page) outcome() string {
+	if p.Error != "" {
+		return "error: " + p.Error
+	}
+	return fmt.Sprint(p.Status)
+}
+
+// runDiff is the diff command, comparing two crawls written in the json or ndjson format
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: monzo diff old.json new.json")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("diff needs an old and a new crawl")
+	}
+	_, before, err := loadCrawl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	_, after, err := loadCrawl(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	return writeDiff(os.Stdout, before, after)
+}
+
+// writeDiff reports the URLs added and removed between two crawls, and those whose status or content changed,
+// then each link in the new crawl to a broken page that wasn't broken or wasn't linked from there before
+func writeDiff(w io.Writer, before, after map[string]*Page) error {
+	var added, removed, statuses, contents []string
+	for _, rawURL := range sortedURLs(after) {
+		page := after[rawURL]
+		if !page.crawled() {
+			continue
+		}
+		old, ok := before[rawURL]
+		if !ok || !old.crawled() {
+			added = append(added, rawURL)
+			continue
+		}
+		if page.outcome() != old.outcome() {
+			statuses = append(statuses, fmt.Sprintf("%s: %s -> %s", rawURL, old.outcome(), page.outcome()))
+		}
+		if page.ContentHash != "" && old.ContentHash != "" && page.ContentHash != old.ContentHash {
+			contents = append(contents, rawURL)
+		}
+	}
+	for _, rawURL := range sortedURLs(before) {
+		if page, ok := after[rawURL]; before[rawURL].crawled() && (!ok || !page.crawled()) {
+			removed = append(removed, rawURL)
+		}
+	}
+	broken := newBrokenLinks(before, after)
+	for _, section := range []struct {
+		name  string
+		lines []string
+	}{
+		{"Added", added},
+		{"Removed", removed},
+		{"Status changed", statuses},
+		{"Content changed", contents},
+		{"New broken links", broken},
+	} {
+		if _, err := fmt.Fprintf(w, "%s (%d):\n", section.name, len(section.lines)); err != nil {
+			return err
+		}
+		for _, line := range section.lines {
+			if _, err := fmt.Fprintln(w, "    "+line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newBrokenLinks describes each link in the new crawl to a broken page that wasn't broken or wasn't linked from there
+// before, in order of the page linking
+func newBrokenLinks(before, after map[string]*Page) []string {
+	var broken []string
+	for _, rawURL := range sortedURLs(after) {
+		page := after[rawURL]
+		for _, link := range page.Links {
+			if !link.broken() {
+				continue
+			}
+			if old, ok := before[rawURL]; ok && linksTo(old, link.URL.String()) && before[link.URL.String()].broken() {
+				continue //already broken from here last time
+			}
+			broken = append(broken, fmt.Sprintf("%s -> %s (%s)", rawURL, link.URL.String(), link.outcome()))
+		}
+	}
+	return broken
+}
+
+func linksTo(page *Page, rawURL string) bool {
+	for _, link := range page.Links {
+		if link.URL.String() == rawURL {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedURLs(pages map[string]*Page) []string {
+	urls := make([]string, 0, len(pages))
+	for rawURL := range pages {
+		urls = append(urls, rawURL)
+	}
+	sort.Strings(urls)
+	return urls
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// dryRun writes what the crawler would make of each of urls without fetching any of them: whether it would fetch it,
+// under what URL, or why it would skip it, and the rule of its origin's robots.txt disallowing it for the user agent
+// if there is one. Seeds are always fetched, the rest only if they are in scope.
+func (c *Crawler) dryRun(w io.Writer, urls []*url.URL) error {
+	c.normaliseSeeds()
+	seeds := make(map[string]bool, len(c.Seeds))
+	for _, seed := range c.Seeds {
+		seeds[seed.String()] = true
+	}
+	fetched := 0
+	listed := make(map[string]bool)
+	variants := make(map[string]string) //normalised URL to the first fetch it matched
+	for _, u := range urls {
+		key := *c.normalise(u)
+		key.Fragment = "" //as parseLink does
+		var line string
+		switch {
+		case !key.IsAbs():
+			line = fmt.Sprintf("skip  %s (not an absolute URL)", u)
+		case listed[key.String()]:
+			line = fmt.Sprintf("skip  %s (already listed as %s)", u, key.String())
+		case !seeds[key.String()] && isOnion(&key) && !c.Onion:
+			line = fmt.Sprintf("skip  %s (onion, without -tor)", u)
+		case !seeds[key.String()] && !c.inScope(&key):
+			line = fmt.Sprintf("skip  %s (out of %s scope)", u, c.Scope)
+		case !seeds[key.String()] && !c.sampled(&key):
+			line = fmt.Sprintf("skip  %s (outside the -sample)", u)
+		case !c.withinBudget(&key):
+			line = fmt.Sprintf("skip  %s (over its -budget)", u)
+		case c.MaxPages > 0 && fetched >= c.MaxPages:
+			line = fmt.Sprintf("skip  %s (over -max-pages)", u)
+		default:
+			fetched++
+			line = "fetch " + key.String()
+			if first, ok := variants[normaliseURL(&key)]; ok {
+				line += " (a variant of " + first + ")"
+			} else {
+				variants[normaliseURL(&key)] = key.String()
+			}
+			if allowed, rule := c.robotsGroup(context.Background(), &key).allowed(&key); !allowed {
+				line += " (disallowed by robots.txt: Disallow: " + rule.pattern + ")"
+			}
+		}
+		listed[key.String()] = true
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	log.Infof("Would fetch %d of %d URLs", fetched, len(urls))
+	return nil
+}
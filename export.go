@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeDOT writes the dot format, the link graph for Graphviz with each page's status as an attribute
+func writeDOT(w io.Writer, root *Page) error {
+	pages := sitePages(root)
+	if _, err := fmt.Fprintf(w, "digraph %q {\n", root.URL.String()); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if _, err := fmt.Fprintf(w, "    %q [status=%d];\n", page.URL.String(), page.Status); err != nil {
+			return err
+		}
+	}
+	for _, page := range pages {
+		for _, link := range page.Links {
+			if _, err := fmt.Fprintf(w, "    %q -> %q;\n", page.URL.String(), link.URL.String()); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// writeGraphML writes the graphml format, the link graph with each page's status, title and scores as node data
+func writeGraphML(w io.Writer, root *Page) error {
+	return writeGraphMLs(w, []*Page{root})
+}
+
+// writeGraphMLs is writeGraphML for the pages under every root, as one graph
+func writeGraphMLs(w io.Writer, roots []*Page) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	graph := struct {
+		XMLName xml.Name `xml:"graphml"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}{XMLNS: "http://graphml.graphdrawing.org/xmlns"}
+	graph.Keys = []key{
+		{"status", "node", "status", "int"},
+		{"title", "node", "title", "string"},
+		{"depth", "node", "click_depth", "int"},
+		{"pagerank", "node", "pagerank", "double"},
+		{"content_type", "node", "content_type", "string"},
+		{"size", "node", "size", "long"},
+		{"fetched", "node", "fetched", "string"},
+		{"latency", "node", "latency_ms", "double"},
+		{"referrer", "node", "referrer", "string"},
+	}
+	graph.Graph.EdgeDefault = "directed"
+	for _, page := range graphPages(roots) {
+		graph.Graph.Nodes = append(graph.Graph.Nodes, node{ID: page.URL.String(), Data: []data{
+			{"status", strconv.Itoa(page.Status)},
+			{"title", page.Title},
+			{"depth", strconv.Itoa(page.ClickDepth)},
+			{"pagerank", strconv.FormatFloat(page.PageRank, 'g', -1, 64)},
+			{"content_type", page.ContentType},
+			{"size", strconv.FormatInt(page.Size, 10)},
+			{"fetched", fetchedTime(page)},
+			{"latency", strconv.FormatFloat(milliseconds(page.Latency), 'g', -1, 64)},
+			{"referrer", page.Referrer},
+		}})
+		for _, link := range page.Links {
+			graph.Graph.Edges = append(graph.Graph.Edges, edge{Source: page.URL.String(), Target: link.URL.String()})
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(graph); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// writeCSV writes the csv format, a row for each page with its status and the basics about it, for spreadsheets
+func writeCSV(w io.Writer, root *Page) error {
+	return writeCSVs(w, []*Page{root})
+}
+
+// writeCSVs is writeCSV for the pages under every root, with the one header row
+func writeCSVs(w io.Writer, roots []*Page) error {
+	pages := graphPages(roots)
+	inlinks, outlinks := linkDegrees(pages)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "status", "error", "content_type", "size", "title", "click_depth", "inlinks", "outlinks", "fetched", "latency_ms", "depth", "referrer"})
+	for _, page := range pages {
+		cw.Write([]string{
+			page.URL.String(),
+			strconv.Itoa(page.Status),
+			page.Error,
+			page.ContentType,
+			strconv.FormatInt(page.Size, 10),
+			page.Title,
+			strconv.Itoa(page.ClickDepth),
+			strconv.Itoa(inlinks[page]),
+			strconv.Itoa(outlinks[page]),
+			fetchedTime(page),
+			strconv.FormatFloat(milliseconds(page.Latency), 'g', -1, 64),
+			strconv.Itoa(page.Depth),
+			page.Referrer,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// fetchedTime is when page was fetched in RFC 3339, empty if it never was
+func fetchedTime(page *Page) string {
+	if page.Fetched.IsZero() {
+		return ""
+	}
+	return page.Fetched.Format(time.RFC3339Nano)
+}
+
+// milliseconds is d in fractional milliseconds, to the microsecond
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// WebmapFilter slices a webmap down to the pages matching all of its set conditions, for graphs too big to look at whole
+type WebmapFilter struct {
+	MaxDepth  int            //most clicks from the seed, -1 for any
+	Path      *regexp.Regexp //matched against the URL's path
+	Statuses  []string       //statuses like 200, or classes like 4xx
+	Component string         //URL of a page, to keep only those in the same strongly connected component as it
+}
+
+func (f *WebmapFilter) matches(page *Page, component map[*Page]struct{}) bool {
+	if f.MaxDepth >= 0 && page.ClickDepth > f.MaxDepth {
+		return false
+	}
+	if f.Path != nil && !f.Path.MatchString(page.URL.Path) {
+		return false
+	}
+	if component != nil {
+		if _, ok := component[page]; !ok {
+			return false
+		}
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	status := strconv.Itoa(page.Status)
+	for _, want := range f.Statuses {
+		if want == status || (strings.HasSuffix(want, "xx") && len(status) == 3 && status[0] == want[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply copies the pages of the webmap under root that match, linked only to each other. The seed is always kept so the
+// slice has somewhere to start from, and any matching page the filter has cut off from it is linked straight from the seed.
+func (f *WebmapFilter) apply(root *Page) *Page {
+	var component map[*Page]struct{}
+	if f.Component != "" {
+		component = make(map[*Page]struct{})
+		for _, scc := range stronglyConnected(sitePages(root)) {
+			if slices.ContainsFunc(scc, func(page *Page) bool { return page.URL.String() == f.Component }) {
+				for _, page := range scc {
+					component[page] = struct{}{}
+				}
+			}
+		}
+	}
+	copies := make(map[*Page]*Page)
+	var kept []*Page
+	for _, page := range sitePages(root) {
+		if page == root || f.matches(page, component) {
+			clone := *page
+			clone.Links = nil
+			copies[page] = &clone
+			kept = append(kept, page)
+		}
+	}
+	for _, page := range kept {
+		for _, link := range page.Links {
+			if linked, ok := copies[link]; ok {
+				copies[page].Links = append(copies[page].Links, linked)
+			}
+		}
+	}
+	reachable := make(map[*Page]struct{})
+	for _, page := range sitePages(copies[root]) {
+		reachable[page] = struct{}{}
+	}
+	for _, page := range kept {
+		if _, ok := reachable[copies[page]]; !ok {
+			copies[root].Links = append(copies[root].Links, copies[page])
+			for _, page := range sitePages(copies[page]) {
+				reachable[page] = struct{}{}
+			}
+		}
+	}
+	return copies[root]
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// otherScheme is u under the other of http and https, dropping a port that was only the default of its own, nil if
+// it's under neither
+func otherScheme(u *url.URL) *url.URL {
+	other := *u
+	switch u.Scheme {
+	case "https":
+		other.Scheme = "http"
+	case "http":
+		other.Scheme = "https"
+	default:
+		return nil
+	}
+	if port := u.Port(); port == "443" && u.Scheme == "https" || port == "80" && u.Scheme == "http" {
+		other.Host = u.Hostname()
+	}
+	return &other
+}
+
+// doFallback does req for target, and if that fails with no response at all does it again under the other scheme, as
+// the crawler's SchemeFallback has it. An origin that only answered under the other scheme goes straight to it from
+// then on, rather than waiting out the retries of a scheme it doesn't serve for every page. Credentials only go with
+// the fallback under https.
+func (c *Crawler) doFallback(ctx context.Context, target *Page, req *http.Request) (*http.Response, error) {
+	other := otherScheme(req.URL)
+	if !c.SchemeFallback || other == nil {
+		return c.do(ctx, req)
+	}
+	origin := req.URL.Scheme + "://" + req.URL.Host
+	var failed error
+	if _, ok := c.fellBack.Load(origin); !ok {
+		resp, err := c.do(ctx, req)
+		if err == nil || ctx.Err() != nil {
+			return resp, err
+		}
+		log.Warningf("failed to get %s, trying %s instead: %v", req.URL.String(), other.Scheme, err)
+		failed = err
+	}
+	fallback, err := http.NewRequestWithContext(ctx, req.Method, other.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	fallback.Header = req.Header.Clone() //its user agent and any If-None-Match and If-Modified-Since from Validators
+	fallback.Header.Del("Authorization")
+	if other.Scheme == "https" && c.inScope(other) { //never in cleartext, falling back to http
+		c.Credentials.apply(fallback)
+	}
+	resp, err := c.do(ctx, fallback)
+	if err != nil {
+		if failed != nil {
+			return nil, fmt.Errorf("%w, and under %s: %v", failed, other.Scheme, err)
+		}
+		return nil, err
+	}
+	c.fellBack.Store(origin, struct{}{})
+	(*target).Fallback = other.String()
+	(*target).used = other
+	return resp, nil
+}
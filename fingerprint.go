@@ -0,0 +1,39 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// volatileWords match the dates and times in a page's text, which change from one fetch to the next without the page really changing
+var volatileWords = []*regexp.Regexp{
+	regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T[\d:.]+)?(Z|[+-]\d{2}:?\d{2})?$`),
+	regexp.MustCompile(`^\d{1,4}[-./]\d{1,2}[-./]\d{1,4},?$`),
+	regexp.MustCompile(`^\d{1,2}:\d{2}(:\d{2})?([ap]m)?,?$`),
+}
+
+// volatileToken matches the long strings of CSRF tokens, session IDs, cache busters and the like, when they mix letters and digits
+var volatileToken = regexp.MustCompile(`^[A-Za-z0-9_\-+/=]{16,}$`)
+
+// fingerprint hashes a page's title and visible text with volatile words left out, so it only differs between fetches when the content does
+func fingerprint(title, text []string) string {
+	var kept []string
+	for _, words := range [][]string{title, text} {
+		for _, word := range words {
+			if !isVolatile(word) {
+				kept = append(kept, strings.ToLower(word))
+			}
+		}
+	}
+	return contentHash([]byte(strings.Join(kept, " ")))
+}
+
+func isVolatile(word string) bool {
+	for _, pattern := range volatileWords {
+		if pattern.MatchString(word) {
+			return true
+		}
+	}
+	return volatileToken.MatchString(word) && strings.IndexFunc(word, unicode.IsDigit) >= 0 && strings.IndexFunc(word, unicode.IsLetter) >= 0
+}
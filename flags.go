@@ -0,0 +1,601 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// stringsFlag is a flag that can be given more than once, collecting every value
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// seedFlags say where the crawl and check commands start and how far they go
+type seedFlags struct {
+	target, seedsPath, scope string
+	maxPages                 int
+	sample                   float64
+	budgets                  stringsFlag
+	budgetRules              []BudgetRule //parsed from budgets by seeds
+	softDeadline             time.Duration
+}
+
+func (f *seedFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.target, "u", "http://www.jkleeman.me", "URL to start crawl on")
+	flags.StringVar(&f.seedsPath, "seeds", "", "Also crawl the seed URLs in this file, one per line, or - for stdin. They share one seen-set")
+	flags.StringVar(&f.scope, "scope", ScopeHost, "Which links to follow: host, domain or prefix")
+	flags.IntVar(&f.maxPages, "max-pages", 0, "Stop fetching after this many pages, 0 for no limit")
+	flags.Float64Var(&f.sample, "sample", 0, "Fetch only this share of the URLs found besides the seeds, e.g. 0.1, picked from them uniformly and the same each crawl, to estimate a large site's metrics quickly")
+	flags.Var(&f.budgets, "budget", "Fetch at most this many URLs matching a regexp, as pattern=limit, e.g. /tag/=500, repeat for each. The first rule a URL matches counts, and those skipped are reported per rule")
+	flags.DurationVar(&f.softDeadline, "soft-deadline", 0, "Stop starting fetches after this long, finishing those in flight and marking the output partial, 0 for no limit")
+}
+
+// limit sets the crawler's budget, its deadline counting from now
+func (f *seedFlags) limit(c *Crawler) {
+	c.MaxPages = f.maxPages
+	c.Budgets = f.budgetRules
+	c.Sample = f.sample
+	if f.softDeadline > 0 {
+		c.Deadline = time.Now().Add(f.softDeadline)
+	}
+}
+
+// seeds collects the URLs to crawl: -u, plus any in the seeds file, plus the arguments.
+// -u is only left out when it wasn't given and there are other seeds.
+func (f *seedFlags) seeds(flags *flag.FlagSet) ([]*url.URL, error) {
+	if !validScope(f.scope) {
+		return nil, fmt.Errorf("unknown scope %s", f.scope)
+	}
+	if f.sample < 0 || f.sample > 1 {
+		return nil, fmt.Errorf("-sample %v isn't a share between 0 and 1", f.sample)
+	}
+	var err error
+	if f.budgetRules, err = parseBudgetRules(f.budgets); err != nil {
+		return nil, err
+	}
+	var seedStrings []string
+	if f.seedsPath != "" {
+		fromFile, err := readLines(f.seedsPath)
+		if err != nil {
+			return nil, err
+		}
+		seedStrings = append(seedStrings, fromFile...)
+	}
+	seedStrings = append(seedStrings, flags.Args()...)
+	explicit := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "u" {
+			explicit = true
+		}
+	})
+	if explicit || len(seedStrings) == 0 {
+		seedStrings = append([]string{f.target}, seedStrings...)
+	}
+	return parseSeeds(seedStrings)
+}
+
+// Profile is a politeness preset, setting how hard a crawl leans on the sites it fetches from in one go
+type Profile struct {
+	Concurrency, HostConcurrency, Retries int
+	Delay, Timeout                        time.Duration
+	Adaptive                              bool
+}
+
+// profiles are the presets -profile picks between
+var profiles = map[string]Profile{
+	"gentle":     {Concurrency: 4, HostConcurrency: 1, Retries: 3, Delay: time.Second, Timeout: time.Minute, Adaptive: true},
+	"default":    {Concurrency: DefaultConcurrency, Retries: 1, Timeout: 30 * time.Second},
+	"aggressive": {Concurrency: 4 * DefaultConcurrency, Timeout: 10 * time.Second},
+}
+
+// fetchFlags are the settings every crawl shares, whichever command started it
+type fetchFlags struct {
+	flags                      *flag.FlagSet //they were registered with, to tell which were given over the profile
+	bodies                     BodyStore     //what configure opened for -store-bodies, for serve's readiness checks
+	profile, strategy, focus   string
+	focusThreshold             float64
+	delay, timeout             time.Duration
+	retries                    int
+	bodyTimeout                time.Duration
+	minRate, maxHTMLSize       int64
+	adaptive, ignoreCrawlDelay bool
+	foldTrailingSlash          bool
+	dedupContent               bool
+	schemeFallback             bool
+	cacheURL                   string
+	cacheTTL                   time.Duration
+	validatorsPath             string
+
+	depth, concurrency, hostConcurrency, certExpiryDays                                                                      int
+	proxy, proxyList, proxyRotate, cookieFile, basicAuth, bearerToken, clientCert, clientKey, caCert, loginURL, loginSuccess string
+	userAgent, userAgentFile, userAgentRotate, bindAddr, tor, grepPattern, scrapeRules, markdownDir, bodiesURL, sincePath    string
+	loginFields, priorityRules, revisitRules                                                                                 stringsFlag
+	insecure, ip4, ip6, mainText, grepHTML, contacts, checkStatics, checkExternal, accessibility, sitemaps, pdfLinks         bool
+}
+
+func (f *fetchFlags) register(flags *flag.FlagSet) {
+	f.flags = flags
+	preset := profiles["default"]
+	flags.IntVar(&f.depth, "d", 5, "How deep the recursive crawler should search")
+	flags.StringVar(&f.profile, "profile", "default", "How hard to lean on the sites crawled: gentle, default or aggressive, setting -concurrency, -host-concurrency, -delay, -retries, -timeout and -adaptive where they aren't given")
+	flags.IntVar(&f.concurrency, "concurrency", preset.Concurrency, "How many pages to fetch at once")
+	flags.IntVar(&f.hostConcurrency, "host-concurrency", preset.HostConcurrency, "How many pages to fetch at once from any one host, 0 for no limit")
+	flags.BoolVar(&f.foldTrailingSlash, "fold-trailing-slash", false, "Treat URLs differing only by a trailing slash, like /docs/ and /docs, as the same page, fetching it once")
+	flags.BoolVar(&f.dedupContent, "dedup-content", false, "Crawl URLs serving identical HTML once, recording the others as aliases of the first instead of following their links")
+	flags.BoolVar(&f.schemeFallback, "scheme-fallback", false, "Fetch pages that fail under https, like on a TLS handshake, over http instead, or the other way round, recording the URL that answered and going straight to that scheme for the rest of the host")
+	flags.StringVar(&f.strategy, "strategy", StrategyBFS, "Order to crawl in: bfs for shallowest first, dfs for newest first, or priority for the highest -priority weight, then fewest path segments, first")
+	flags.Var(&f.priorityRules, "priority", "Weigh URLs matching a regexp for -strategy priority as pattern=weight, e.g. /product/=10 or ^/tag/=-5, repeat for each. Implies -strategy priority")
+	flags.DurationVar(&f.delay, "delay", preset.Delay, "Least time between starting fetches from any one host")
+	flags.IntVar(&f.retries, "retries", preset.Retries, "How many more times to try a page after network errors, 429s and 5xxs, backing off between tries")
+	flags.DurationVar(&f.bodyTimeout, "body-timeout", 0, "Give up on reading an HTML page's body after this long and retry it, 0 for no limit besides -timeout")
+	flags.Int64Var(&f.minRate, "min-rate", 0, "Give up on an HTML page's body and retry it if it arrives at under this many bytes a second after its first 5s, 0 for no minimum")
+	flags.Int64Var(&f.maxHTMLSize, "max-html-size", 0, "Only read this many bytes of an HTML page, following the links in them and marking the page truncated if it had more, 0 for no limit")
+	flags.BoolVar(&f.ignoreCrawlDelay, "ignore-crawl-delay", false, "Pace fetches by -delay alone, even from sites whose robots.txt asks for a longer Crawl-delay")
+	flags.BoolVar(&f.adaptive, "adaptive", preset.Adaptive, "Halve how many pages are fetched at once from a host whenever it answers slowly, with a 429 or a 5xx, or not at all, growing it back as the host recovers")
+	flags.DurationVar(&f.timeout, "timeout", preset.Timeout, "Longest fetching any one page may take, 0 for no limit")
+	flags.StringVar(&f.proxy, "proxy", "", "Fetch through this proxy, http://, https:// or socks5://, with any credentials as user:pass@")
+	flags.StringVar(&f.proxyList, "proxy-list", "", "Fetch through the proxies in this file, one per line, rotating between them")
+	flags.StringVar(&f.proxyRotate, "proxy-rotate", RotatePerRequest, "How to rotate -proxy-list: request for the next proxy every request, host to stick to one per host")
+	flags.StringVar(&f.cookieFile, "cookies", "", "Start with the cookies in this file, in Netscape cookies.txt or JSON export format")
+	flags.StringVar(&f.basicAuth, "basic-auth", "", "Send these user:pass credentials with HTTP basic auth, to URLs within scope only")
+	flags.StringVar(&f.bearerToken, "bearer-token", "", "Send this bearer token in the Authorization header, to URLs within scope only")
+	flags.StringVar(&f.clientCert, "client-cert", "", "Present this PEM certificate to servers asking for one, for mutual TLS")
+	flags.StringVar(&f.clientKey, "client-key", "", "PEM private key for -client-cert")
+	flags.StringVar(&f.caCert, "ca-cert", "", "Also trust the PEM CA certificates in this file, for private CAs")
+	flags.BoolVar(&f.insecure, "insecure-skip-verify", false, "Fetch from servers whose certificates don't verify, still recording the failure on each page")
+	flags.StringVar(&f.loginURL, "login-url", "", "Before crawling, log in through the form on this page so the crawl has a session")
+	flags.Var(&f.loginFields, "login-field", "A name=value to fill in on the login form, repeat for each field")
+	flags.StringVar(&f.loginSuccess, "login-success", "", "Regexp the login response body or URL must match for the login to count")
+	flags.StringVar(&f.userAgent, "user-agent", "", "Fetch with this User-Agent instead of Go's default")
+	flags.StringVar(&f.userAgentFile, "user-agents", "", "Rotate between the user agents in this file, one per line")
+	flags.StringVar(&f.userAgentRotate, "user-agent-rotate", RotatePerRequest, "How to rotate -user-agents: request for the next one every request, host to stick to one per host")
+	flags.BoolVar(&f.ip4, "ip4", false, "Only connect over IPv4")
+	flags.BoolVar(&f.ip6, "ip6", false, "Only connect over IPv6")
+	flags.StringVar(&f.tor, "tor", "", "Fetch everything through the Tor SOCKS port at this host:port, such as 127.0.0.1:9050, allowing .onion hosts")
+	flags.StringVar(&f.bindAddr, "bind-addr", "", "Local IP address to make connections from")
+	flags.StringVar(&f.cacheURL, "cache", "", "Keep responses in this Redis (e.g. redis://localhost:6379/0) for -cache-ttl, and take pages from it rather than fetch them again, so crawls sharing it or restarted needn't")
+	flags.DurationVar(&f.cacheTTL, "cache-ttl", 24*time.Hour, "How long -cache keeps each response")
+	flags.StringVar(&f.validatorsPath, "validators", "", "Keep each page's ETag and Last-Modified in this file, and fetch pages found there only if they were modified since, recording those that weren't with a 304 and following the links they had")
+	flags.BoolVar(&f.mainText, "main-text", false, "Extract each page's main content and its word count, readability style")
+	flags.StringVar(&f.grepPattern, "grep", "", "Search every page's text for this regexp, reporting matching lines in the grep format unless -format says otherwise")
+	flags.BoolVar(&f.grepHTML, "grep-html", false, "Make -grep search each page's raw HTML rather than its text")
+	flags.StringVar(&f.scrapeRules, "scrape", "", "Extract fields from every page with the CSS selector or XPath rules in this JSON file")
+	flags.BoolVar(&f.contacts, "contacts", false, "Collect the email addresses and phone numbers on every page, reported in the contacts format unless -format says otherwise")
+	flags.BoolVar(&f.checkExternal, "check-external", false, "HEAD every link out of the crawl's scope once, recording its status. -audit external implies this")
+	flags.BoolVar(&f.checkStatics, "check-statics", false, "HEAD every static each page refers to, recording its status and size. -audit assets and weight imply this")
+	flags.IntVar(&f.certExpiryDays, "cert-expiry-days", int(DefaultCertExpiryWarning/(24*time.Hour)), "Warn about certificates expiring within this many days")
+	flags.BoolVar(&f.accessibility, "accessibility", false, "Check every page for missing alt text, empty links, unlabelled form fields and a missing lang. -audit accessibility implies this")
+	flags.BoolVar(&f.sitemaps, "sitemaps", false, "Fetch each seed's /sitemap.xml, recording the URLs it lists. -audit orphans implies this")
+	flags.StringVar(&f.markdownDir, "markdown", "", "Convert each page's main content to Markdown, saved under this directory laid out like its URL")
+	flags.StringVar(&f.focus, "focus", "", "Only follow links from the seeds and pages about these comma separated keywords or phrases, for harvesting one topic from a large site")
+	flags.Float64Var(&f.focusThreshold, "focus-threshold", 0.5, "Share of the -focus keywords a page needs in its title or text for its links to be followed")
+	flags.BoolVar(&f.pdfLinks, "pdf-links", false, "Follow the links in linked PDFs as well as in pages")
+	flags.StringVar(&f.bodiesURL, "store-bodies", "", "Keep every fetched body, gzipped and named by the sha256 of its URL, in this directory or s3://bucket/prefix or gs://bucket/prefix, for parsing again later without refetching")
+	flags.Var(&f.revisitRules, "revisit", "With -since, carry over pages matching a regexp from the earlier crawl rather than fetch them, until they are older than an interval, as pattern=interval, e.g. /news/=hourly or /docs/=weekly, repeat for each. The first rule a URL matches counts")
+	flags.StringVar(&f.sincePath, "since", "", "Mark each page changed or not since this earlier crawl, written in the json or ndjson format, going by a fingerprint of its text that ignores dates, times and tokens")
+}
+
+func (f *fetchFlags) certExpiry() time.Duration {
+	return time.Duration(f.certExpiryDays) * 24 * time.Hour
+}
+
+// userAgents are the -user-agent and those in -user-agent-file, nil if neither is given
+func (f *fetchFlags) userAgents() (*UserAgents, error) {
+	var agents []string
+	if f.userAgent != "" {
+		agents = append(agents, f.userAgent)
+	}
+	if f.userAgentFile != "" {
+		fromFile, err := readLines(f.userAgentFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read user agents: %w", err)
+		}
+		agents = append(agents, fromFile...)
+	}
+	if len(agents) == 0 {
+		return nil, nil
+	}
+	userAgents, err := NewUserAgents(agents, f.userAgentRotate)
+	if err != nil {
+		return nil, fmt.Errorf("bad user agents: %w", err)
+	}
+	return userAgents, nil
+}
+
+// configure sets up what the flags ask for, such as the HTTP client and any login, returning how to apply it to each crawler.
+// audit is the report the crawl is for, turning on the checks it needs.
+func (f *fetchFlags) configure(audit string) (func(*Crawler), error) {
+	profile, ok := profiles[f.profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %s", f.profile)
+	}
+	given := make(map[string]bool)
+	f.flags.Visit(func(flag *flag.Flag) {
+		given[flag.Name] = true
+	})
+	if !validStrategy(f.strategy) {
+		return nil, fmt.Errorf("unknown strategy %s", f.strategy)
+	}
+	priorityRules, err := parsePriorityRules(f.priorityRules)
+	if err != nil {
+		return nil, err
+	}
+	if len(priorityRules) > 0 && !given["strategy"] {
+		f.strategy = StrategyPriority
+	}
+	if !given["concurrency"] {
+		f.concurrency = profile.Concurrency
+	}
+	if !given["host-concurrency"] {
+		f.hostConcurrency = profile.HostConcurrency
+	}
+	if !given["delay"] {
+		f.delay = profile.Delay
+	}
+	if !given["retries"] {
+		f.retries = profile.Retries
+	}
+	if !given["timeout"] {
+		f.timeout = profile.Timeout
+		if f.tor != "" && f.timeout > 0 { //so the profile's limit is for the request, not the circuit coming up
+			f.timeout += torDialTimeout + torTLSHandshakeTimeout
+		}
+	}
+	if !given["adaptive"] {
+		f.adaptive = profile.Adaptive
+	}
+	roots, err := loadRootCAs(f.caCert)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load CA certificates: %w", err)
+	}
+	clientOpts := ClientOptions{
+		RootCAs:     roots,
+		Insecure:    f.insecure,
+		ProxyRotate: f.proxyRotate,
+		CookieFile:  f.cookieFile,
+		ClientCert:  f.clientCert,
+		ClientKey:   f.clientKey,
+		BindAddr:    f.bindAddr,
+		Tor:         f.tor,
+		Timeout:     f.timeout,
+		Cache:       f.cacheURL,
+		CacheTTL:    f.cacheTTL,
+	}
+	switch {
+	case f.ip4 && f.ip6:
+		return nil, errors.New("-ip4 and -ip6 can't both be used")
+	case f.ip4:
+		clientOpts.IPFamily = IPv4
+	case f.ip6:
+		clientOpts.IPFamily = IPv6
+	}
+	if f.proxy != "" {
+		clientOpts.Proxies = append(clientOpts.Proxies, f.proxy)
+	}
+	if f.proxyList != "" {
+		proxies, err := readLines(f.proxyList)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read proxy list: %w", err)
+		}
+		clientOpts.Proxies = append(clientOpts.Proxies, proxies...)
+	}
+	client, err := NewClient(clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't set up the HTTP client: %w", err)
+	}
+	if f.loginURL != "" {
+		opts := LoginOptions{URL: f.loginURL, Fields: make(map[string]string)}
+		for _, field := range f.loginFields {
+			name, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("login fields should look like name=value: %s", field)
+			}
+			opts.Fields[name] = value
+		}
+		if f.loginSuccess != "" {
+			if opts.Success, err = regexp.Compile(f.loginSuccess); err != nil {
+				return nil, fmt.Errorf("bad login success regexp: %w", err)
+			}
+		}
+		if err := login(context.Background(), client, opts); err != nil {
+			return nil, fmt.Errorf("couldn't log in: %w", err)
+		}
+	}
+	credentials, err := parseCredentials(f.basicAuth, f.bearerToken)
+	if err != nil {
+		return nil, fmt.Errorf("bad credentials: %w", err)
+	}
+	userAgents, err := f.userAgents()
+	if err != nil {
+		return nil, err
+	}
+	var grep *regexp.Regexp
+	if f.grepPattern != "" {
+		if grep, err = regexp.Compile(f.grepPattern); err != nil {
+			return nil, fmt.Errorf("bad grep regexp: %w", err)
+		}
+	}
+	var rules []*ScrapeRule
+	if f.scrapeRules != "" {
+		if rules, err = loadScrapeRules(f.scrapeRules); err != nil {
+			return nil, fmt.Errorf("couldn't load scrape rules: %w", err)
+		}
+	}
+	var bodies BodyStore
+	if f.bodiesURL != "" {
+		if bodies, err = openBodyStore(f.bodiesURL); err != nil {
+			return nil, fmt.Errorf("couldn't open body store: %w", err)
+		}
+	}
+	f.bodies = bodies
+	var validators *Validators
+	if f.validatorsPath != "" {
+		if validators, err = LoadValidators(f.validatorsPath); err != nil {
+			return nil, fmt.Errorf("couldn't read validators: %w", err)
+		}
+	}
+	revisitRules, err := parseRevisitRules(f.revisitRules)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisitRules) > 0 && f.sincePath == "" {
+		return nil, errors.New("-revisit needs the earlier crawl to reuse pages from, given by -since")
+	}
+	var previous map[string]string
+	var prior map[string]*Page
+	if f.sincePath != "" {
+		_, prior, err = loadCrawl(f.sincePath)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read earlier crawl: %w", err)
+		}
+		previous = make(map[string]string, len(prior))
+		for rawURL, page := range prior {
+			previous[rawURL] = page.Fingerprint
+		}
+	}
+	return func(c *Crawler) {
+		c.Concurrency = f.concurrency
+		c.HostConcurrency = f.hostConcurrency
+		c.Delay = f.delay
+		c.Retries = f.retries
+		c.BodyTimeout = f.bodyTimeout
+		c.MinRate = f.minRate
+		c.MaxHTMLSize = f.maxHTMLSize
+		c.Adaptive = f.adaptive
+		c.IgnoreCrawlDelay = f.ignoreCrawlDelay
+		c.Frontier = newMemoryFrontier(f.strategy)
+		c.FoldTrailingSlash = f.foldTrailingSlash
+		c.DedupContent = f.dedupContent
+		c.SchemeFallback = f.schemeFallback
+		if len(priorityRules) > 0 {
+			c.Priority = rulePriority(priorityRules)
+		}
+		c.Client = client
+		c.Credentials = credentials
+		c.TLSRoots = roots
+		c.UserAgents = userAgents
+		c.Onion = f.tor != ""
+		c.MainText = f.mainText
+		c.Markdown = f.markdownDir
+		c.PDFLinks = f.pdfLinks
+		if f.focus != "" {
+			c.Focus = NewFocus(f.focus, f.focusThreshold)
+		}
+		c.Bodies = bodies
+		c.Previous = previous
+		c.Validators = validators
+		if len(revisitRules) > 0 {
+			c.Prior, c.Revisit = prior, revisitRules
+		}
+		c.Grep, c.GrepHTML = grep, f.grepHTML
+		c.Scrape = rules
+		c.Contacts = f.contacts
+		c.CertExpiryWarning = f.certExpiry()
+		c.Accessibility = f.accessibility || auditsCheckingAccessibility[audit]
+		c.Sitemaps = f.sitemaps || auditsFetchingSitemaps[audit]
+		c.CheckStatics = f.checkStatics || auditsCheckingStatics[audit]
+		c.CheckExternal = f.checkExternal || auditsCheckingExternal[audit]
+	}, nil
+}
+
+// outputFlags say what to make of the webmaps once they are crawled or loaded, for the crawl and report commands
+type outputFlags struct {
+	format, audit, outFile, failOn, uploadURL, perSeedDir, filterPath, filterStatus, filterComponent, visualizeAddr string
+	weightBudget, maxRedirectHops, maxClickDepth, topPages, pageRankIterations, hitsIterations, filterDepth         int
+	damping                                                                                                         float64
+	pageRank, hits                                                                                                  bool
+	outs                                                                                                            stringsFlag
+	visualized                                                                                                      chan error //what -visualize stopped with, once it's serving
+}
+
+func (f *outputFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.format, "format", "", "Write the webmap to stdout in this format ("+strings.Join(formatNames(), ", ")+") instead of logging it")
+	flags.StringVar(&f.outFile, "o", "", "Also write the webmap to this file, in the format its extension says ("+strings.Join(extensionNames(), ", ")+"). Without -format nothing else is written")
+	flags.Var(&f.outs, "out", "Also write the webmap to a file as format=path, e.g. dot=site.dot, repeat for each file. Without -format nothing else is written")
+	flags.StringVar(&f.uploadURL, "upload", "", "Upload the webmap to this object (s3://bucket/key or gs://bucket/key) instead of writing it to stdout, as json unless -format says otherwise")
+	flags.StringVar(&f.perSeedDir, "per-seed", "", "Write each seed's webmap to its own file in this directory, as json unless -format says otherwise")
+	flags.StringVar(&f.audit, "audit", "", "Write this report on the crawled site ("+strings.Join(auditNames(), ", ")+") instead of the webmap")
+	flags.IntVar(&f.weightBudget, "weight-budget", 0, "KB a page and its statics may add up to before -audit weight flags it, 0 for no budget")
+	flags.IntVar(&f.maxRedirectHops, "max-redirect-hops", 1, "Longest redirect chain -audit redirects lets pass without flagging")
+	flags.IntVar(&f.maxClickDepth, "max-click-depth", 3, "Most clicks from the seed -audit depth lets a page be without flagging it")
+	flags.IntVar(&f.topPages, "top", 10, "How many pages -audit links lists as the most and the least linked to")
+	flags.BoolVar(&f.pageRank, "pagerank", false, "Score each page by PageRank over the crawl's links once it is done, for the webmap's pagerank field")
+	flags.Float64Var(&f.damping, "pagerank-damping", 0.85, "Chance a -pagerank surfer follows a link rather than jumping to any page")
+	flags.IntVar(&f.pageRankIterations, "pagerank-iterations", 50, "How many rounds -pagerank runs for")
+	flags.BoolVar(&f.hits, "hits", false, "Score each page as a hub and an authority by HITS over the crawl's links once it is done, for the webmap's hub and authority fields")
+	flags.IntVar(&f.hitsIterations, "hits-iterations", 50, "How many rounds -hits runs for")
+	flags.IntVar(&f.filterDepth, "filter-depth", -1, "Only output pages at most this many clicks from the seed, -1 for any")
+	flags.StringVar(&f.filterPath, "filter-path", "", "Only output pages whose path matches this regular expression")
+	flags.StringVar(&f.filterStatus, "filter-status", "", "Only output pages with one of these statuses, e.g. 404,5xx")
+	flags.StringVar(&f.filterComponent, "filter-component", "", "Only output pages that can reach and be reached from this URL by links")
+	flags.StringVar(&f.failOn, "fail-on", "", "Exit with an error once the webmap is output if the crawl passes any of these thresholds, like broken-links>0,errors>5%. They can be on "+strings.Join(gateMetricNames(), ", "))
+	flags.StringVar(&f.visualizeAddr, "visualize", "", "Once the crawl is output, serve a drawing of its link graph to explore in a browser on this address (e.g. localhost:8070)")
+}
+
+// output returns what to do with the webmaps: score them, slice them down and write them out, or package them in archive if
+// that is set. defaultFormat is used without -format, then json for anything written to a file.
+func (f *outputFlags) output(defaultFormat string, certExpiry time.Duration, archive *Archive) (func([]*Page), error) {
+	format := f.format
+	if format == "" {
+		format = defaultFormat
+	}
+	if format == "" && (f.uploadURL != "" || f.perSeedDir != "" || archive != nil) {
+		format = "json"
+	}
+	write, ok := formats[format]
+	if format != "" && !ok {
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+	if f.audit != "" {
+		writeAudit, ok := audits[f.audit]
+		if !ok {
+			return nil, fmt.Errorf("unknown audit %s", f.audit)
+		}
+		opts := AuditOptions{WeightBudget: int64(f.weightBudget) << 10, MaxRedirectHops: f.maxRedirectHops, CertExpiryWarning: certExpiry, TopPages: f.topPages, MaxClickDepth: f.maxClickDepth}
+		write = func(w io.Writer, page *Page) error { return writeAudit(w, page, opts) }
+		format = "text" //for the file extension and content type, audits being plain text reports
+	}
+	type target struct {
+		path  string
+		write func(io.Writer, []*Page) error
+	}
+	var targets []target
+	for _, out := range f.outs {
+		name, path, ok := strings.Cut(out, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("-out should look like format=path: %s", out)
+		}
+		write, ok := formats[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown format %s in -out %s", name, out)
+		}
+		targets = append(targets, target{path, webmapWriter(name, write)})
+	}
+	if f.outFile != "" {
+		name, ok := formatExtensions[strings.ToLower(filepath.Ext(f.outFile))]
+		if !ok {
+			return nil, fmt.Errorf("can't tell the format of -o %s from its extension", f.outFile)
+		}
+		targets = append(targets, target{f.outFile, webmapWriter(name, formats[name])})
+	}
+	var thresholds []Threshold
+	if f.failOn != "" {
+		var err error
+		if thresholds, err = parseThresholds(f.failOn); err != nil {
+			return nil, fmt.Errorf("bad -fail-on: %w", err)
+		}
+	}
+	var filter *WebmapFilter
+	if f.filterDepth >= 0 || f.filterPath != "" || f.filterStatus != "" || f.filterComponent != "" {
+		filter = &WebmapFilter{MaxDepth: f.filterDepth, Component: f.filterComponent}
+		if f.filterPath != "" {
+			var err error
+			if filter.Path, err = regexp.Compile(f.filterPath); err != nil {
+				return nil, fmt.Errorf("invalid -filter-path: %w", err)
+			}
+		}
+		if f.filterStatus != "" {
+			filter.Statuses = strings.Split(f.filterStatus, ",")
+		}
+	}
+	return func(pages []*Page) {
+		for page, depth := range clickDepths(pages) {
+			page.ClickDepth = depth
+		}
+		for _, page := range pages {
+			page.Structure = siteStats(page)
+		}
+		if f.pageRank {
+			computePageRank(pages, f.damping, f.pageRankIterations)
+		}
+		if f.hits {
+			computeHITS(pages, f.hitsIterations)
+		}
+		all := pages
+		if filter != nil { //scores, statistics and thresholds stay those of the whole crawl
+			filtered := make([]*Page, len(pages))
+			for i, page := range pages {
+				filtered[i] = filter.apply(page)
+			}
+			pages = filtered
+		}
+		for _, target := range targets {
+			err := writeFile(target.path, func(w io.Writer) error { return target.write(w, pages) })
+			if err != nil {
+				log.Error("couldn't write webmap:", err)
+				os.Exit(1)
+			}
+			log.Info("Wrote webmap to", target.path)
+		}
+		switch {
+		case archive != nil:
+			if err := archive.Close(pages, format, webmapWriter(format, write)); err != nil {
+				log.Error("couldn't write archive:", err)
+				os.Exit(1)
+			}
+			log.Info("Archived crawl to", archive.f.Name())
+		case write != nil || len(targets) == 0: //-out alone doesn't log the webmap as well
+			outputWebmaps(pages, format, write, f.uploadURL, f.perSeedDir)
+		}
+		for _, page := range pages {
+			logSiteStats(page, page.Structure)
+		}
+		if failed := failedThresholds(all, thresholds); len(failed) > 0 {
+			for _, failure := range failed {
+				log.Error("failed -fail-on", failure)
+			}
+			os.Exit(1)
+		}
+		if f.visualizeAddr != "" { //served in the background, so -inspect and the rest of the crawl's logging go ahead
+			listener, err := net.Listen("tcp", f.visualizeAddr)
+			if err != nil {
+				log.Error("couldn't visualise the crawl:", err)
+				os.Exit(1)
+			}
+			f.visualized = make(chan error, 1)
+			go func() {
+				f.visualized <- visualize(listener, pages)
+			}()
+		}
+	}, nil
+}
+
+// writes reports whether the webmaps will be written in format, to stdout or any file, so the crawl knows to keep what
+// only that format needs
+func (f *outputFlags) writes(format string) bool {
+	if f.format == format || f.outFile != "" && formatExtensions[strings.ToLower(filepath.Ext(f.outFile))] == format {
+		return true
+	}
+	for _, out := range f.outs {
+		if name, _, _ := strings.Cut(out, "="); name == format {
+			return true
+		}
+	}
+	return false
+}
+
+// wait blocks for as long as -visualize serves the output, exiting once it stops
+func (f *outputFlags) wait() {
+	if f.visualized == nil {
+		return
+	}
+	log.Error("visualisation stopped:", <-f.visualized)
+	os.Exit(1)
+}
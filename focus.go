@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Focus keeps a crawl to a topic, following links only from pages relevant enough to its keywords
+type Focus struct {
+	Keywords  []string //lower case words or phrases
+	Threshold float64  //least Relevance a page needs for its links to be followed
+}
+
+// NewFocus makes a Focus on comma separated keywords, like "solar, wind power, turbines"
+func NewFocus(keywords string, threshold float64) *Focus {
+	f := &Focus{Threshold: threshold}
+	for _, keyword := range strings.Split(keywords, ",") {
+		if words := focusWords(strings.Fields(keyword)); len(words) > 0 {
+			f.Keywords = append(f.Keywords, strings.Join(words, " "))
+		}
+	}
+	return f
+}
+
+// relevance is the share of the keywords that appear in a page's title or visible text, as whole words
+func (f *Focus) relevance(title, text []string) float64 {
+	if len(f.Keywords) == 0 {
+		return 0
+	}
+	page := " " + strings.Join(focusWords(title), " ") + " " + strings.Join(focusWords(text), " ") + " "
+	found := 0
+	for _, keyword := range f.Keywords {
+		if strings.Contains(page, " "+keyword+" ") {
+			found++
+		}
+	}
+	return float64(found) / float64(len(f.Keywords))
+}
+
+// focusWords lower cases words and trims the punctuation around them, dropping any left empty
+func focusWords(words []string) []string {
+	var trimmed []string
+	for _, word := range words {
+		word = strings.TrimFunc(strings.ToLower(word), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if word != "" {
+			trimmed = append(trimmed, word)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// FrontierItem is a URL waiting to be crawled, with the depth left to crawl below it
+type FrontierItem struct {
+	URL      string  `json:"url"`
+	Depth    int     `json:"depth"`
+	Priority float64 `json:"priority,omitempty"` //higher is crawled sooner, under StrategyPriority
+	Referrer string  `json:"referrer,omitempty"` //URL of the page it was found on
+}
+
+// strategies decide the order an in-memory frontier hands out each host's URLs in
+const (
+	StrategyBFS      = "bfs"      //shallowest first, so each page is reached by its shortest path from a seed
+	StrategyDFS      = "dfs"      //most recently discovered first
+	StrategyPriority = "priority" //highest Priority first, shallowest among equals
+)
+
+func validStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyBFS, StrategyDFS, StrategyPriority:
+		return true
+	}
+	return false
+}
+
+// Frontier holds the URLs a crawl has yet to fetch along with every URL it has seen.
+// Implementations may be shared between crawler processes cooperating on one crawl.
+type Frontier interface {
+	// Push queues the item unless its URL has been seen before, reporting whether it was new
+	Push(ctx context.Context, item FrontierItem) (bool, error)
+	// Pop blocks until an item is available, returning nil once nothing is queued or in progress anywhere
+	Pop(ctx context.Context) (*FrontierItem, error)
+	// Done marks a popped item as finished, after everything it discovered has been pushed
+	Done(ctx context.Context, item FrontierItem) error
+	// Seen returns the number of unique URLs pushed so far
+	Seen(ctx context.Context) (int, error)
+	// MarkSeen adds a URL to the seen URLs without queuing it, so pushing it later does nothing, reporting whether it was new
+	MarkSeen(ctx context.Context, rawURL string) (bool, error)
+}
+
+// urlPriority ranks a URL for StrategyPriority, those with fewer path segments first and a query counting as one more,
+// as a site's hub pages tend to sit near its root
+func urlPriority(u *url.URL) float64 {
+	segments := len(strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' }))
+	if u.RawQuery != "" {
+		segments++
+	}
+	return -float64(segments)
+}
+
+// memoryFrontier is a Frontier private to one crawl in this process. It keeps a queue per host and pops from each in
+// turn, so a host with thousands of URLs queued doesn't hold up discovery on the others.
+type memoryFrontier struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	strategy string
+	seen     map[string]struct{} //valueless map, for checking if URL has already been seen
+	hosts    map[string]*frontierQueue
+	rotation []string //hosts with items queued, in the order they are next popped from
+	pushed   int      //items ever pushed, to order items the strategy ranks equally
+	pending  int      //items pushed but not yet done, so workers know whether more might arrive
+}
+
+func newMemoryFrontier(strategy string) *memoryFrontier {
+	f := &memoryFrontier{strategy: strategy, seen: make(map[string]struct{}), hosts: make(map[string]*frontierQueue)}
+	f.cond = sync.NewCond(&f.mutex)
+	return f
+}
+
+// frontierQueue is a heap of one host's items waiting to be popped, ordered by its strategy
+type frontierQueue struct {
+	strategy string
+	items    []queuedItem
+}
+
+type queuedItem struct {
+	FrontierItem
+	order int //when it was pushed
+}
+
+func (q *frontierQueue) Len() int      { return len(q.items) }
+func (q *frontierQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *frontierQueue) Push(x any)    { q.items = append(q.items, x.(queuedItem)) }
+
+func (q *frontierQueue) Pop() any {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
+
+func (q *frontierQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	switch {
+	case q.strategy == StrategyDFS:
+		return a.order > b.order
+	case q.strategy == StrategyPriority && a.Priority != b.Priority:
+		return a.Priority > b.Priority
+	case a.Depth != b.Depth: //depth is what's left to crawl below an item, so the shallowest have the most
+		return a.Depth > b.Depth
+	}
+	return a.order < b.order
+}
+
+func (f *memoryFrontier) Push(ctx context.Context, item FrontierItem) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, ok := f.seen[item.URL]; ok {
+		return false, nil
+	}
+	f.seen[item.URL] = struct{}{}
+	var host string
+	if u, err := url.Parse(item.URL); err == nil {
+		host = u.Host
+	}
+	queue, ok := f.hosts[host]
+	if !ok {
+		queue = &frontierQueue{strategy: f.strategy}
+		f.hosts[host] = queue
+		f.rotation = append(f.rotation, host)
+	}
+	heap.Push(queue, queuedItem{FrontierItem: item, order: f.pushed})
+	f.pushed++
+	f.pending++
+	f.cond.Signal()
+	return true, nil
+}
+
+func (f *memoryFrontier) Pop(ctx context.Context) (*FrontierItem, error) {
+	stop := context.AfterFunc(ctx, func() { //wake waiting workers if the crawl is cancelled
+		f.mutex.Lock()
+		f.cond.Broadcast()
+		f.mutex.Unlock()
+	})
+	defer stop()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for len(f.rotation) == 0 && f.pending > 0 && ctx.Err() == nil {
+		f.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(f.rotation) == 0 { //nothing queued and nothing in progress, so the crawl is over
+		f.cond.Broadcast()
+		return nil, nil
+	}
+	host := f.rotation[0]
+	queue := f.hosts[host]
+	item := heap.Pop(queue).(queuedItem).FrontierItem
+	f.rotation = f.rotation[1:]
+	if queue.Len() > 0 { //back of the line
+		f.rotation = append(f.rotation, host)
+	} else {
+		delete(f.hosts, host)
+	}
+	return &item, nil
+}
+
+func (f *memoryFrontier) Done(ctx context.Context, item FrontierItem) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.pending--
+	if f.pending == 0 {
+		f.cond.Broadcast()
+	}
+	return nil
+}
+
+func (f *memoryFrontier) Seen(ctx context.Context) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.seen), nil
+}
+
+func (f *memoryFrontier) MarkSeen(ctx context.Context, rawURL string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, ok := f.seen[rawURL]; ok {
+		return false, nil
+	}
+	f.seen[rawURL] = struct{}{}
+	return true, nil
+}
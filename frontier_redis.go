@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// redisFrontier shares a crawl's queue and seen-set between every process pointed at the same keys.
+// The pending counter covers items queued or in progress on any process, so all of them agree on when the crawl is over.
+type redisFrontier struct {
+	client  *redis.Client
+	seen    string //set of every URL pushed
+	queue   string //list of JSON encoded FrontierItems
+	pending string //count of items pushed but not yet done
+	ttl     int64  //seconds the keys last after the last push, 0 for ever
+}
+
+// pushScript adds the URL to the seen-set and only queues it if it wasn't already there, atomically, putting off when
+// the keys expire if they do
+var pushScript = redis.NewScript(`
+if redis.call("SADD", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("RPUSH", KEYS[2], ARGV[2])
+redis.call("INCR", KEYS[3])
+if tonumber(ARGV[3]) > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call("EXPIRE", key, ARGV[3])
+	end
+end
+return 1
+`)
+
+// markSeenScript adds the URL to the seen-set, putting off when it expires as pushScript does, so a crawl only marking
+// URLs seen for a while doesn't lose them
+var markSeenScript = redis.NewScript(`
+local added = redis.call("SADD", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then
+	redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+return added
+`)
+
+// redisPollInterval is how long an idle worker blocks on the queue before checking whether the crawl has finished
+const redisPollInterval = time.Second
+
+func newRedisFrontier(ctx context.Context, redisURL, name string, ttl time.Duration) (*redisFrontier, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	prefix := "monzo:crawl:" + name + ":"
+	return &redisFrontier{client: client, seen: prefix + "seen", queue: prefix + "queue", pending: prefix + "pending", ttl: int64(ttl.Seconds())}, nil
+}
+
+func (f *redisFrontier) Push(ctx context.Context, item FrontierItem) (bool, error) {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return false, err
+	}
+	added, err := pushScript.Run(ctx, f.client, []string{f.seen, f.queue, f.pending}, item.URL, encoded, f.ttl).Int()
+	return added == 1, err
+}
+
+func (f *redisFrontier) Pop(ctx context.Context) (*FrontierItem, error) {
+	for {
+		popped, err := f.client.BLPop(ctx, redisPollInterval, f.queue).Result()
+		if err == redis.Nil { //timed out, see if anyone still has work that could produce more
+			pending, err := f.client.Get(ctx, f.pending).Int()
+			if err != nil && err != redis.Nil {
+				return nil, err
+			}
+			if pending <= 0 {
+				return nil, nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var item FrontierItem
+		if err := json.Unmarshal([]byte(popped[1]), &item); err != nil {
+			return nil, err
+		}
+		return &item, nil
+	}
+}
+
+func (f *redisFrontier) Done(ctx context.Context, item FrontierItem) error {
+	return f.client.Decr(ctx, f.pending).Err()
+}
+
+func (f *redisFrontier) Seen(ctx context.Context) (int, error) {
+	seen, err := f.client.SCard(ctx, f.seen).Result()
+	return int(seen), err
+}
+
+func (f *redisFrontier) MarkSeen(ctx context.Context, rawURL string) (bool, error) {
+	added, err := markSeenScript.Run(ctx, f.client, []string{f.seen}, rawURL, f.ttl).Int()
+	return added == 1, err
+}
+
+func (f *redisFrontier) Close() error {
+	return f.client.Close()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gateMetrics count what -fail-on thresholds can be set on, over every page of a crawl
+var gateMetrics = map[string]func(pages []*Page) int{
+	"pages":                countPages(func(page *Page) bool { return page.crawled() }),
+	"errors":               countPages(func(page *Page) bool { return page.broken() }),
+	"4xx":                  countPages(func(page *Page) bool { return page.Status >= 400 && page.Status < 500 }),
+	"5xx":                  countPages(func(page *Page) bool { return page.Status >= 500 }),
+	"redirects":            countPages(func(page *Page) bool { return len(page.Redirects) > 0 }),
+	"missing-titles":       countPages(func(page *Page) bool { return page.parsed() && page.Title == "" }),
+	"missing-descriptions": countPages(func(page *Page) bool { return page.parsed() && page.Description == "" }),
+	"mixed-content":        countPages(func(page *Page) bool { return len(page.MixedContent) > 0 }),
+	"accessibility-issues": countPages(func(page *Page) bool { return len(page.AccessibilityIssues) > 0 }),
+	"changed":              countPages(func(page *Page) bool { return page.Changed != nil && *page.Changed }),
+	"broken-links": func(pages []*Page) int {
+		outcomes, _ := brokenLinks(pages)
+		return len(outcomes)
+	},
+}
+
+func countPages(matches func(*Page) bool) func([]*Page) int {
+	return func(pages []*Page) int {
+		n := 0
+		for _, page := range pages {
+			if matches(page) {
+				n++
+			}
+		}
+		return n
+	}
+}
+
+// parsed reports whether the page is HTML that was fetched successfully, so has a title and the like to check
+func (p *Page) parsed() bool {
+	return p.isHTML() && p.Status >= 200 && p.Status <= 299
+}
+
+func gateMetricNames() []string {
+	names := make([]string, 0, len(gateMetrics))
+	for name := range gateMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Threshold is a limit on one of the gateMetrics that a crawl fails by going past
+type Threshold struct {
+	Spec    string //as it was given, like errors>5%
+	Metric  string
+	Op      string //>, >=, < or <=
+	Value   float64
+	Percent bool //whether Value is a percentage of the pages crawled rather than a count
+}
+
+var thresholdPattern = regexp.MustCompile(`^([a-z0-9-]+)\s*(>=|<=|>|<)\s*([0-9]+(?:\.[0-9]+)?)(%?)$`)
+
+// parseThresholds parses a comma separated list of thresholds, like broken-links>0,errors>5%
+func parseThresholds(spec string) ([]Threshold, error) {
+	var thresholds []Threshold
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		m := thresholdPattern.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("threshold %q should look like metric>count or metric>percent%%", part)
+		}
+		if _, ok := gateMetrics[m[1]]; !ok {
+			return nil, fmt.Errorf("unknown metric %s, try one of %s", m[1], strings.Join(gateMetricNames(), ", "))
+		}
+		value, _ := strconv.ParseFloat(m[3], 64)
+		thresholds = append(thresholds, Threshold{Spec: part, Metric: m[1], Op: m[2], Value: value, Percent: m[4] == "%"})
+	}
+	return thresholds, nil
+}
+
+// failedThresholds measures the crawl under roots against each threshold, describing those it trips
+func failedThresholds(roots []*Page, thresholds []Threshold) []string {
+	pages := graphPages(roots)
+	crawled := gateMetrics["pages"](pages)
+	var failed []string
+	for _, t := range thresholds {
+		count := gateMetrics[t.Metric](pages)
+		value, shown := float64(count), strconv.Itoa(count)
+		if t.Percent {
+			value = 0
+			if crawled > 0 {
+				value = 100 * float64(count) / float64(crawled)
+			}
+			shown = fmt.Sprintf("%d, %.1f%% of %d pages", count, value, crawled)
+		}
+		var tripped bool
+		switch t.Op {
+		case ">":
+			tripped = value > t.Value
+		case ">=":
+			tripped = value >= t.Value
+		case "<":
+			tripped = value < t.Value
+		case "<=":
+			tripped = value <= t.Value
+		}
+		if tripped {
+			failed = append(failed, fmt.Sprintf("%s: %s is %s", t.Spec, t.Metric, shown))
+		}
+	}
+	return failed
+}
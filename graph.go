@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// linkDegrees counts the distinct other pages linking to each page and that each page links to, leaving out links to itself
+func linkDegrees(pages []*Page) (inlinks, outlinks map[*Page]int) {
+	inlinks, outlinks = make(map[*Page]int, len(pages)), make(map[*Page]int, len(pages))
+	for _, page := range pages {
+		for _, link := range page.Links {
+			if link != page {
+				inlinks[link]++
+				outlinks[page]++
+			}
+		}
+	}
+	return inlinks, outlinks
+}
+
+// writeLinkAudit reports the most and least linked to of the successfully fetched pages, with how many pages link to and from each
+func writeLinkAudit(w io.Writer, root *Page, opts AuditOptions) error {
+	pages := sitePages(root)
+	inlinks, outlinks := linkDegrees(pages)
+	var ranked []*Page
+	for _, page := range pages {
+		if page != root && page.Status >= 200 && page.Status <= 299 { //the seed is where the crawl started, not somewhere to be linked to
+			ranked = append(ranked, page)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if inlinks[ranked[i]] != inlinks[ranked[j]] {
+			return inlinks[ranked[i]] > inlinks[ranked[j]]
+		}
+		return ranked[i].URL.String() < ranked[j].URL.String()
+	})
+	top := min(opts.TopPages, len(ranked))
+	least := make([]*Page, top)
+	for i := range least {
+		least[i] = ranked[len(ranked)-1-i]
+	}
+	for _, section := range []struct {
+		heading string
+		pages   []*Page
+	}{
+		{"Most linked to:", ranked[:top]},
+		{"Least linked to:", least},
+	} {
+		if len(section.pages) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, section.heading); err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "    page\tinlinks\toutlinks")
+		for _, page := range section.pages {
+			fmt.Fprintf(tw, "    %s\t%d\t%d\n", page.URL, inlinks[page], outlinks[page])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// graphPages lists every page reachable from any of roots once
+func graphPages(roots []*Page) []*Page {
+	var pages []*Page
+	seen := make(map[*Page]struct{})
+	for _, root := range roots {
+		for _, page := range sitePages(root) {
+			if _, ok := seen[page]; !ok {
+				seen[page] = struct{}{}
+				pages = append(pages, page)
+			}
+		}
+	}
+	return pages
+}
+
+// computePageRank sets each page's PageRank over the links between them, the scores summing to 1.
+// Pages without links, not least those beyond the crawl's depth, share their score out across every page.
+func computePageRank(roots []*Page, damping float64, iterations int) {
+	pages := graphPages(roots)
+	if len(pages) == 0 {
+		return
+	}
+	n := float64(len(pages))
+	index := make(map[*Page]int, len(pages))
+	for i, page := range pages {
+		index[page] = i
+	}
+	_, outlinks := linkDegrees(pages)
+	rank, next := make([]float64, len(pages)), make([]float64, len(pages))
+	for i := range rank {
+		rank[i] = 1 / n
+	}
+	for range iterations {
+		dangling := 0.0
+		for i, page := range pages {
+			if outlinks[page] == 0 {
+				dangling += rank[i]
+			}
+		}
+		for i := range next {
+			next[i] = (1-damping)/n + damping*dangling/n
+		}
+		for i, page := range pages {
+			for _, link := range page.Links {
+				if link != page {
+					next[index[link]] += damping * rank[i] / float64(outlinks[page])
+				}
+			}
+		}
+		rank, next = next, rank
+	}
+	for i, page := range pages {
+		page.PageRank = rank[i]
+	}
+}
+
+// clickDepths finds how few links each page is from the nearest of roots, breadth first
+func clickDepths(roots []*Page) map[*Page]int {
+	depths := make(map[*Page]int)
+	var queue []*Page
+	for _, root := range roots {
+		if _, ok := depths[root]; !ok {
+			depths[root] = 0
+			queue = append(queue, root)
+		}
+	}
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+		for _, link := range page.Links {
+			if _, ok := depths[link]; !ok {
+				depths[link] = depths[page] + 1
+				queue = append(queue, link)
+			}
+		}
+	}
+	return depths
+}
+
+// writeClickDepthAudit reports the successfully fetched pages more clicks from the seed than the threshold, deepest first
+func writeClickDepthAudit(w io.Writer, root *Page, opts AuditOptions) error {
+	depths := clickDepths([]*Page{root})
+	var deep []*Page
+	for _, page := range sitePages(root) {
+		if depths[page] > opts.MaxClickDepth && page.Status >= 200 && page.Status <= 299 {
+			deep = append(deep, page)
+		}
+	}
+	sort.SliceStable(deep, func(i, j int) bool { return depths[deep[i]] > depths[deep[j]] })
+	if len(deep) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "More than %d clicks from %s:\n", opts.MaxClickDepth, root.URL); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "    page\tclicks")
+	for _, page := range deep {
+		fmt.Fprintf(tw, "    %s\t%d\n", page.URL, depths[page])
+	}
+	return tw.Flush()
+}
+
+// stronglyConnected splits pages into their strongly connected components by Tarjan's algorithm, each a set of pages that can all reach each other
+func stronglyConnected(pages []*Page) [][]*Page {
+	index, lowlink := make(map[*Page]int), make(map[*Page]int)
+	onStack := make(map[*Page]bool)
+	var stack []*Page
+	var components [][]*Page
+	var connect func(*Page)
+	connect = func(page *Page) {
+		index[page], lowlink[page] = len(index), len(index)
+		stack = append(stack, page)
+		onStack[page] = true
+		for _, link := range page.Links {
+			if _, ok := index[link]; !ok {
+				connect(link)
+				lowlink[page] = min(lowlink[page], lowlink[link])
+			} else if onStack[link] {
+				lowlink[page] = min(lowlink[page], index[link])
+			}
+		}
+		if lowlink[page] != index[page] {
+			return
+		}
+		var component []*Page
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == page {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+	for _, page := range pages {
+		if _, ok := index[page]; !ok {
+			connect(page)
+		}
+	}
+	return components
+}
+
+// writeCycleAudit reports each group of pages that link round in a loop, largest first,
+// marking those with no links out of the group, which visitors and crawlers that wander in can't leave
+func writeCycleAudit(w io.Writer, root *Page, _ AuditOptions) error {
+	var cycles [][]*Page
+	for _, component := range stronglyConnected(sitePages(root)) {
+		if len(component) > 1 {
+			cycles = append(cycles, component)
+		}
+	}
+	sort.SliceStable(cycles, func(i, j int) bool { return len(cycles[i]) > len(cycles[j]) })
+	for _, cycle := range cycles {
+		members := make(map[*Page]struct{}, len(cycle))
+		for _, page := range cycle {
+			members[page] = struct{}{}
+		}
+		closed := true
+		for _, page := range cycle {
+			for _, link := range page.Links {
+				if _, ok := members[link]; !ok {
+					closed = false
+				}
+			}
+		}
+		sort.Slice(cycle, func(i, j int) bool { return cycle[i].URL.String() < cycle[j].URL.String() })
+		heading := fmt.Sprintf("%d pages linking round in a loop", len(cycle))
+		if _, ok := members[root]; ok {
+			heading += ", including the seed"
+		} else if closed {
+			heading += ", with no links out of it"
+		}
+		if _, err := fmt.Fprintln(w, heading+":"); err != nil {
+			return err
+		}
+		for _, page := range cycle {
+			if _, err := fmt.Fprintln(w, "    "+page.URL.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SiteStats sums up the shape of a crawled site
+type SiteStats struct {
+	Pages           int            `json:"pages"`            //fetched successfully
+	Depths          []int          `json:"depths"`           //how many of the pages are each number of clicks from the seed
+	BranchingFactor float64        `json:"branching_factor"` //mean links per page to other pages in the crawl
+	Sections        map[string]int `json:"sections"`         //pages by the first segment of their path, / for those at the top
+	Statics         int            `json:"statics"`          //distinct statics the pages refer to
+	StaticsPerPage  float64        `json:"statics_per_page"`
+}
+
+func siteStats(root *Page) *SiteStats {
+	stats := &SiteStats{Sections: make(map[string]int)}
+	depths := clickDepths([]*Page{root})
+	_, outlinks := linkDegrees(sitePages(root))
+	statics := make(map[string]struct{})
+	links := 0
+	for _, page := range sitePages(root) {
+		if page.Status < 200 || page.Status > 299 {
+			continue
+		}
+		stats.Pages++
+		for len(stats.Depths) <= depths[page] {
+			stats.Depths = append(stats.Depths, 0)
+		}
+		stats.Depths[depths[page]]++
+		links += outlinks[page]
+		section := "/"
+		if first, _, ok := strings.Cut(strings.TrimPrefix(page.URL.Path, "/"), "/"); ok {
+			section = "/" + first + "/"
+		}
+		stats.Sections[section]++
+		for _, static := range page.Statics {
+			statics[static.String()] = struct{}{}
+		}
+	}
+	stats.Statics = len(statics)
+	if stats.Pages > 0 {
+		stats.BranchingFactor = float64(links) / float64(stats.Pages)
+		stats.StaticsPerPage = float64(stats.Statics) / float64(stats.Pages)
+	}
+	return stats
+}
+
+// logSiteStats writes a site's structure to the log as part of the crawl's summary
+func logSiteStats(root *Page, stats *SiteStats) {
+	log.Infof("Structure of %s: %d pages, %.1f links and %.1f statics per page, %d distinct statics", displayURL(root.URL.String()), stats.Pages, stats.BranchingFactor, stats.StaticsPerPage, stats.Statics)
+	for depth, count := range stats.Depths {
+		log.Infof("    %d pages %d clicks deep", count, depth)
+	}
+	sections := make([]string, 0, len(stats.Sections))
+	for section := range stats.Sections {
+		sections = append(sections, section)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		if stats.Sections[sections[i]] != stats.Sections[sections[j]] {
+			return stats.Sections[sections[i]] > stats.Sections[sections[j]]
+		}
+		return sections[i] < sections[j]
+	})
+	for _, section := range sections {
+		log.Infof("    %d pages under %s", stats.Sections[section], section)
+	}
+}
+
+// computeHITS sets each page's Hub and Authority scores by Kleinberg's HITS over the links between them, each normalised to unit length.
+// Good hubs link to many good authorities, like navigation and index pages, and good authorities are linked to by many good hubs.
+func computeHITS(roots []*Page, iterations int) {
+	pages := graphPages(roots)
+	hub, authority := make(map[*Page]float64, len(pages)), make(map[*Page]float64, len(pages))
+	for _, page := range pages {
+		hub[page], authority[page] = 1, 1
+	}
+	normalise := func(scores map[*Page]float64) {
+		total := 0.0
+		for _, score := range scores {
+			total += score * score
+		}
+		if total == 0 {
+			return
+		}
+		norm := math.Sqrt(total)
+		for page := range scores {
+			scores[page] /= norm
+		}
+	}
+	for range iterations {
+		next := make(map[*Page]float64, len(pages))
+		for _, page := range pages {
+			for _, link := range page.Links {
+				if link != page {
+					next[link] += hub[page]
+				}
+			}
+		}
+		normalise(next)
+		authority = next
+		next = make(map[*Page]float64, len(pages))
+		for _, page := range pages {
+			for _, link := range page.Links {
+				if link != page {
+					next[page] += authority[link]
+				}
+			}
+		}
+		normalise(next)
+		hub = next
+	}
+	for _, page := range pages {
+		page.Hub, page.Authority = hub[page], authority[page]
+	}
+}
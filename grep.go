@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// grepContext is how much of a matching line either side of the match is kept in its snippet
+const grepContext = 60
+
+// GrepMatch is a line of a page that matched the crawl's -grep
+type GrepMatch struct {
+	Line    int    `json:"line"` //line of the page's HTML the match is on
+	Snippet string `json:"snippet"`
+}
+
+// grepLines returns a match for each line of text that re matches, numbering them from firstLine
+func grepLines(re *regexp.Regexp, text string, firstLine int) []GrepMatch {
+	var matches []GrepMatch
+	for i, line := range strings.Split(text, "\n") {
+		if loc := re.FindStringIndex(line); loc != nil {
+			matches = append(matches, GrepMatch{Line: firstLine + i, Snippet: snippet(line, loc)})
+		}
+	}
+	return matches
+}
+
+// snippet cuts a long line down to the match and some context either side
+func snippet(line string, loc []int) string {
+	start, end := max(loc[0]-grepContext, 0), min(loc[1]+grepContext, len(line))
+	for start > 0 && !isRuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !isRuneStart(line[end]) {
+		end++
+	}
+	s := strings.TrimSpace(line[start:end])
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(line) {
+		s += "..."
+	}
+	return s
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// writeGrep writes the grep format, a url:line: snippet line for every match on every page, like grep -n over the site
+func writeGrep(w io.Writer, root *Page) error {
+	for _, page := range sitePages(root) {
+		for _, match := range page.Matches {
+			if _, err := fmt.Fprintf(w, "%s:%d: %s\n", page.URL.String(), match.Line, match.Snippet); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"github.com/jackkleeman/monzo/crawlerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"time"
+)
+
+type grpcServer struct {
+	crawlerpb.UnimplementedCrawlerServer
+	jobs *JobManager
+}
+
+// serveGRPC exposes the crawler over the gRPC API in crawlerpb until the listener fails
+func serveGRPC(listener net.Listener, jobs *JobManager) error {
+	server := grpc.NewServer(grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{ //allow the coordinator's liveness pings
+		MinTime:             5 * time.Second,
+		PermitWithoutStream: true,
+	}))
+	crawlerpb.RegisterCrawlerServer(server, &grpcServer{jobs: jobs})
+	log.Infof("serving crawl gRPC API on %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+func (s *grpcServer) SubmitCrawl(ctx context.Context, req *crawlerpb.SubmitCrawlRequest) (*crawlerpb.SubmitCrawlResponse, error) {
+	job, code, message := s.jobs.submitRequest(crawlRequest{Seed: req.Seed, Depth: int(req.Depth), Scope: req.Scope, MaxPages: int(req.MaxPages), Sink: req.Sink, FoldTrailingSlash: req.FoldTrailingSlash})
+	if job == nil {
+		if code == http.StatusServiceUnavailable {
+			return nil, status.Error(codes.ResourceExhausted, message)
+		}
+		return nil, status.Error(codes.InvalidArgument, message)
+	}
+	return &crawlerpb.SubmitCrawlResponse{Id: job.ID}, nil
+}
+
+// StreamResults sends pages as the crawl finishes them. Each Send blocks on gRPC flow control,
+// so a slow client only ever holds back its own stream while the pages wait in the job.
+func (s *grpcServer) StreamResults(req *crawlerpb.StreamResultsRequest, stream crawlerpb.Crawler_StreamResultsServer) error {
+	sent := 0
+	for {
+		pages, stopped, updated, ok := s.jobs.Pages(req.Id, sent)
+		if !ok {
+			return status.Error(codes.NotFound, "no such crawl")
+		}
+		for _, page := range pages {
+			if err := stream.Send(pageResult(page)); err != nil {
+				return err
+			}
+			sent++
+		}
+		if stopped && len(pages) == 0 {
+			return nil
+		}
+		if len(pages) > 0 { //check for more before waiting, they may have arrived while sending
+			continue
+		}
+		select {
+		case <-updated:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *grpcServer) CancelCrawl(ctx context.Context, req *crawlerpb.CancelCrawlRequest) (*crawlerpb.CancelCrawlResponse, error) {
+	job, ok := s.jobs.Cancel(req.Id)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no such crawl")
+	}
+	return &crawlerpb.CancelCrawlResponse{Status: job.Status}, nil
+}
+
+func pageResult(page *Page) *crawlerpb.PageResult {
+	result := &crawlerpb.PageResult{
+		Url:         page.URL.String(),
+		Title:       page.Title,
+		Description: page.Description,
+		Keywords:    page.Keywords,
+	}
+	for _, static := range page.Statics {
+		result.Statics = append(result.Statics, static.String())
+	}
+	for _, link := range page.Links {
+		result.Links = append(result.Links, link.URL.String())
+	}
+	return result
+}
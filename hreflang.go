@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// writeHreflang writes the hreflang format: a matrix of each page's alternates by locale,
+// then every alternate that wasn't crawled successfully or doesn't list the page back
+func writeHreflang(w io.Writer, root *Page) error {
+	pages := make(map[string]*Page)
+	var order []*Page
+	var walk func(*Page)
+	walk = func(page *Page) {
+		if _, ok := pages[page.URL.String()]; ok {
+			return
+		}
+		pages[page.URL.String()] = page
+		order = append(order, page)
+		for _, link := range page.Links {
+			walk(link)
+		}
+	}
+	walk(root)
+	locales := make(map[string]struct{})
+	var withAlternates []*Page
+	for _, page := range order {
+		if len(page.Alternates) > 0 {
+			withAlternates = append(withAlternates, page)
+		}
+		for _, alternate := range page.Alternates {
+			locales[alternate.Lang] = struct{}{}
+		}
+	}
+	langs := make([]string, 0, len(locales))
+	for lang := range locales {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "page\t%s\n", strings.Join(langs, "\t"))
+	var problems []string
+	for _, page := range withAlternates {
+		byLang := make(map[string]string)
+		for _, alternate := range page.Alternates {
+			byLang[alternate.Lang] = alternate.URL
+			if alternate.URL == page.URL.String() {
+				continue
+			}
+			other, ok := pages[alternate.URL]
+			switch {
+			case !ok || (other.Fetched.IsZero() && other.Error == ""): //unreached, or beyond the crawl's depth
+				problems = append(problems, fmt.Sprintf("%s lists %s as %s, which wasn't crawled", page.URL, alternate.URL, alternate.Lang))
+			case other.Status < 200 || other.Status > 299:
+				problems = append(problems, fmt.Sprintf("%s lists %s as %s, which returned %s", page.URL, alternate.URL, alternate.Lang, fetchOutcome(other)))
+			case !listsAlternate(other, page.URL.String()):
+				problems = append(problems, fmt.Sprintf("%s lists %s as %s, which doesn't list it back", page.URL, alternate.URL, alternate.Lang))
+			}
+		}
+		row := []string{page.URL.String()}
+		for _, lang := range langs {
+			if u, ok := byLang[lang]; ok {
+				row = append(row, u)
+			} else {
+				row = append(row, "-")
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "Problems:"); err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		if _, err := fmt.Fprintln(w, "    "+problem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listsAlternate(page *Page, rawURL string) bool {
+	for _, alternate := range page.Alternates {
+		if alternate.URL == rawURL {
+			return true
+		}
+	}
+	return false
+}
+
+func fetchOutcome(page *Page) string {
+	if page.Error != "" {
+		return "an error: " + page.Error
+	}
+	return fmt.Sprintf("status %d", page.Status)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// htmlReport is a standalone page summarising a crawl, for reading in a browser straight from a bucket
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Crawl of {{.Seeds}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+.broken { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Crawl of {{.Seeds}}</h1>
+<p>{{len .Pages}} pages, {{len .Broken}} broken links.</p>
+<h2>Statuses</h2>
+<table>
+<tr><th>Status</th><th>Pages</th></tr>
+{{range .Statuses}}<tr><td>{{.Status}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{if .Broken}}<h2>Broken links</h2>
+<table>
+<tr><th>URL</th><th>Outcome</th><th>Linked from</th></tr>
+{{range .Broken}}<tr class="broken"><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Outcome}}</td><td>{{range .Sources}}<a href="{{.}}">{{.}}</a><br>{{end}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Pages</h2>
+<table>
+<tr><th>URL</th><th>Status</th><th>Title</th><th>Click depth</th><th>Inlinks</th><th>Outlinks</th><th>Size</th><th>Latency (ms)</th></tr>
+{{range .Pages}}<tr{{if .Broken}} class="broken"{{end}}><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Status}}</td><td>{{.Title}}</td><td>{{.ClickDepth}}</td><td>{{.Inlinks}}</td><td>{{.Outlinks}}</td><td>{{.Size}}</td><td>{{.LatencyMS}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type htmlReportPage struct {
+	URL, Status, Title            string
+	ClickDepth, Inlinks, Outlinks int
+	Size                          int64
+	LatencyMS                     float64
+	Broken                        bool
+}
+
+type htmlReportLink struct {
+	URL, Outcome string
+	Sources      []string
+}
+
+type htmlReportStatus struct {
+	Status string
+	Count  int
+}
+
+// writeHTMLReport writes the pages under root as an HTML report
+func writeHTMLReport(w io.Writer, root *Page) error {
+	return writeHTMLReports(w, []*Page{root})
+}
+
+// writeHTMLReports writes the pages under every root as one HTML report: how many came back with each status, the
+// brokenLinks and where they're linked from, then every page
+func writeHTMLReports(w io.Writer, roots []*Page) error {
+	pages := graphPages(roots)
+	inlinks, outlinks := linkDegrees(pages)
+	var data struct {
+		Seeds    string
+		Pages    []htmlReportPage
+		Statuses []htmlReportStatus
+		Broken   []htmlReportLink
+	}
+	for i, root := range roots {
+		if i > 0 {
+			data.Seeds += ", "
+		}
+		data.Seeds += displayURL(root.URL.String())
+	}
+	counts := make(map[string]int)
+	for _, page := range pages {
+		status := strconv.Itoa(page.Status)
+		if page.Error != "" {
+			status = page.Error
+		} else if page.Status == 0 {
+			status = "not fetched"
+		}
+		counts[status]++
+		data.Pages = append(data.Pages, htmlReportPage{
+			URL:        displayURL(page.URL.String()),
+			Status:     status,
+			Title:      page.Title,
+			ClickDepth: page.ClickDepth,
+			Inlinks:    inlinks[page],
+			Outlinks:   outlinks[page],
+			Size:       page.Size,
+			LatencyMS:  milliseconds(page.Latency),
+			Broken:     page.broken(),
+		})
+	}
+	for status, count := range counts {
+		data.Statuses = append(data.Statuses, htmlReportStatus{Status: status, Count: count})
+	}
+	sort.Slice(data.Statuses, func(i, j int) bool { return data.Statuses[i].Status < data.Statuses[j].Status })
+	outcomes, sources := brokenLinks(roots)
+	for rawURL, outcome := range outcomes {
+		link := htmlReportLink{URL: displayURL(rawURL), Outcome: outcome}
+		for _, source := range sources[rawURL] {
+			link.Sources = append(link.Sources, displayURL(source))
+		}
+		data.Broken = append(data.Broken, link)
+	}
+	sort.Slice(data.Broken, func(i, j int) bool { return data.Broken[i].URL < data.Broken[j].URL })
+	return htmlReport.Execute(w, data)
+}
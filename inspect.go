@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// inspectHelp lists the inspector's commands
+const inspectHelp = `pages [status]                list the pages, or only those with a status like 404, 4xx or error
+show <url>                    what the crawl found on a page
+links <url>                   the pages a page links to
+inlinks <url>                 the pages linking to a page
+refetch <url>                 fetch a page again, updating what the crawl found on it
+export <url> <format> <path>  write the webmap under a page to a file
+help                          this list
+quit                          stop inspecting
+URLs can be given relative to the first seed.`
+
+// inspect reads commands querying the webmaps under roots until the input ends or it is told to quit, for looking into
+// one corner of a site without crawling it again. crawler is what refetches pages, nil if they can't be.
+func inspect(in io.Reader, out io.Writer, roots []*Page, crawler *Crawler) {
+	if len(roots) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "Inspecting the crawl, type help for the commands")
+	scanner := bufio.NewScanner(in)
+	for fmt.Fprint(out, "> "); scanner.Scan(); fmt.Fprint(out, "> ") {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		pages := graphPages(roots)
+		var page *Page
+		if len(args) > 1 {
+			if page = findPage(pages, roots[0].URL, args[1]); page == nil && args[0] != "pages" {
+				fmt.Fprintln(out, "not in the crawl:", args[1])
+				continue
+			}
+		}
+		switch command := args[0]; command {
+		case "quit", "exit":
+			return
+		case "help":
+			fmt.Fprintln(out, inspectHelp)
+		case "pages":
+			filter := &WebmapFilter{MaxDepth: -1}
+			if len(args) > 1 && args[1] != "error" {
+				filter.Statuses = []string{args[1]}
+			}
+			for _, page := range pages {
+				if len(args) > 1 && args[1] == "error" && page.Error == "" || !filter.matches(page, nil) {
+					continue
+				}
+				fmt.Fprintf(out, "%s %s\n", inspectStatus(page), page.URL)
+			}
+		case "show", "links", "inlinks", "refetch", "export":
+			if page == nil {
+				fmt.Fprintln(out, command, "needs a URL")
+				continue
+			}
+			inspectPage(out, command, args, page, pages, crawler)
+		default:
+			fmt.Fprintln(out, "unknown command, type help for the commands")
+		}
+	}
+}
+
+// inspectPage runs one of the inspector's commands on a page
+func inspectPage(out io.Writer, command string, args []string, page *Page, pages []*Page, crawler *Crawler) {
+	switch {
+	case command == "show":
+		showPage(out, page, pages)
+	case command == "links":
+		for _, link := range page.Links {
+			fmt.Fprintf(out, "%s %s\n", inspectStatus(link), link.URL)
+		}
+	case command == "inlinks":
+		for _, source := range pages {
+			if slices.Contains(source.Links, page) {
+				fmt.Fprintf(out, "%s %s\n", inspectStatus(source), source.URL)
+			}
+		}
+	case command == "refetch" && crawler == nil:
+		fmt.Fprintln(out, "pages can only be refetched straight after crawling them")
+	case command == "refetch":
+		crawler.refetch(context.Background(), page)
+		fmt.Fprintf(out, "%s %s\n", inspectStatus(page), page.URL)
+	case command == "export" && len(args) == 4:
+		write, ok := formats[args[2]]
+		if !ok {
+			fmt.Fprintln(out, "unknown format", args[2])
+			return
+		}
+		if err := writeFile(args[3], func(w io.Writer) error { return write(w, page) }); err != nil {
+			fmt.Fprintln(out, "couldn't export:", err)
+			return
+		}
+		fmt.Fprintf(out, "wrote %d pages to %s\n", len(sitePages(page)), args[3])
+	case command == "export":
+		fmt.Fprintln(out, "export needs a URL, a format and a path")
+	}
+}
+
+// inspectStatus is how fetching a page went, for listing it
+func inspectStatus(page *Page) string {
+	if !page.crawled() {
+		return "not fetched"
+	}
+	return page.outcome()
+}
+
+// findPage finds a page by its URL, which may be relative to base
+func findPage(pages []*Page, base *url.URL, rawURL string) *Page {
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	target := base.ResolveReference(ref).String()
+	for _, page := range pages {
+		if page.URL.String() == target {
+			return page
+		}
+	}
+	return nil
+}
+
+func showPage(out io.Writer, page *Page, pages []*Page) {
+	inlinks, outlinks := linkDegrees(pages)
+	fmt.Fprintln(out, page.URL)
+	for _, field := range []struct {
+		name, value string
+	}{
+		{"status", inspectStatus(page)},
+		{"content type", page.ContentType},
+		{"size", formatBytes(page.Size)},
+		{"title", page.Title},
+		{"description", page.Description},
+		{"clicks from the seed", fmt.Sprint(page.ClickDepth)},
+		{"links", fmt.Sprint(outlinks[page])},
+		{"inlinks", fmt.Sprint(inlinks[page])},
+		{"statics", fmt.Sprint(len(page.Statics))},
+		{"external links", fmt.Sprint(len(page.ExternalLinks))},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(out, "    %s: %s\n", field.name, field.value)
+		}
+	}
+	for _, redirect := range page.Redirects {
+		fmt.Fprintf(out, "    redirected %s -> %s (%d)\n", redirect.From, redirect.To, redirect.Status)
+	}
+}
+
+// refetch fetches page again, replacing what the crawl found on it. Links to pages the crawl hasn't seen are added but not
+// followed, as when the crawl ran out of depth.
+func (c *Crawler) refetch(ctx context.Context, page *Page) {
+	c.MaxPages = 0 //the crawl is over, so its budget has done its job
+	fresh := &Page{URL: page.URL, ClickDepth: page.ClickDepth}
+	c.crawlPage(ctx, fresh, 1)
+	*page = *fresh
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+	JobFailed    = "failed"
+)
+
+// DefaultMaxJobs is how many crawls a JobManager runs at once unless told otherwise
+const DefaultMaxJobs = 4
+
+type Job struct {
+	ID                string    `json:"id"`
+	Seed              string    `json:"seed"`
+	Depth             int       `json:"depth"`
+	Scope             string    `json:"scope"`
+	MaxPages          int       `json:"max_pages,omitempty"`
+	Sink              string    `json:"sink,omitempty"`
+	Email             []string  `json:"email,omitempty"`
+	FoldTrailingSlash *bool     `json:"fold_trailing_slash,omitempty"` //overriding the server's own setting, if set
+	Status            string    `json:"status"`
+	Error             string    `json:"error,omitempty"`
+	Seen              int       `json:"seen"`
+	Created           time.Time `json:"created"`
+	Started           time.Time `json:"started"`
+	Finished          time.Time `json:"finished"`
+
+	seed    *url.URL
+	cancel  context.CancelFunc
+	stopped bool          //set once the crawl has returned, or the job was cancelled before it started
+	result  *Page         //the site map, available once stopped unless the job never ran
+	pages   []*Page       //every fetched page in the order they finished, for streaming
+	updated chan struct{} //closed and replaced whenever pages or status change
+}
+
+// JobSettings are the process-wide settings every job runs with
+type JobSettings struct {
+	Configure func(*Crawler) //applies the crawl flags to each job's crawler
+	Depth     int            //for jobs that don't give one
+	Bodies    BodyStore      //where jobs keep the bodies they fetch, if anywhere, for Ready to check
+	Mailer    *Mailer        //if set, mails each job's summary to its Email addresses once it's done
+}
+
+// JobManager queues submitted crawls and runs up to MaxJobs of them at once in the background.
+// Every job gets a crawler of its own, so jobs share nothing but the process.
+type JobManager struct {
+	mutex    sync.Mutex
+	settings JobSettings //replaced by Reconfigure, so only read with the lock held
+	jobs     map[string]*Job
+	nextID   int
+	queue    chan *Job
+}
+
+func NewJobManager(maxJobs int, settings JobSettings) *JobManager {
+	if maxJobs <= 0 {
+		maxJobs = DefaultMaxJobs
+	}
+	m := &JobManager{settings: settings, jobs: make(map[string]*Job), queue: make(chan *Job, 100)}
+	for i := 0; i < maxJobs; i++ {
+		go m.run()
+	}
+	return m
+}
+
+// notify wakes anyone waiting on the job, must be called with the lock held
+func (j *Job) notify() {
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
+
+func (m *JobManager) run() {
+	for job := range m.queue {
+		m.mutex.Lock()
+		if job.stopped { //cancelled while still queued
+			m.mutex.Unlock()
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		settings := m.settings //kept for the whole job, whatever Reconfigure does meanwhile
+		job.Status = JobRunning
+		job.Started = time.Now()
+		job.cancel = cancel
+		job.notify()
+		m.mutex.Unlock()
+		m.crawl(ctx, job, settings.Configure)
+		cancel()
+		m.mutex.Lock()
+		job.Finished = time.Now()
+		job.stopped = true
+		job.notify()
+		m.mutex.Unlock()
+		log.Infof("job %s %s crawling %s in %s", job.ID, job.Status, job.Seed, job.Finished.Sub(job.Started))
+		if settings.Mailer != nil && len(job.Email) > 0 && job.result != nil { //settled now the job has stopped
+			announceCrawl(Announcement{Mailer: settings.Mailer, MailTo: job.Email}, []*Page{job.result}, job.Finished.Sub(job.Started))
+		}
+	}
+}
+
+func (m *JobManager) crawl(ctx context.Context, job *Job, configure func(*Crawler)) {
+	crawler := NewCrawler([]*url.URL{job.seed}, job.Depth, job.Scope)
+	configure(crawler)
+	crawler.MaxPages = job.MaxPages
+	if job.FoldTrailingSlash != nil {
+		crawler.FoldTrailingSlash = *job.FoldTrailingSlash
+	}
+	var sink Sink
+	if job.Sink != "" {
+		var err error
+		if sink, err = openSink(job.Sink); err != nil {
+			m.mutex.Lock()
+			job.Status = JobFailed
+			job.Error = "couldn't open sink: " + err.Error()
+			m.mutex.Unlock()
+			return
+		}
+		crawler.KeepText = true
+	}
+	crawler.OnPage = func(page *Page) {
+		if sink != nil {
+			if err := sink.Write(ctx, page); err != nil {
+				log.Errorf("job %s failed to write %s to sink: %v", job.ID, page.URL.String(), err)
+			}
+		}
+		m.mutex.Lock()
+		job.pages = append(job.pages, page)
+		job.notify()
+		m.mutex.Unlock()
+	}
+	result := crawler.Run(ctx)[0]
+	if sink != nil {
+		if err := sink.Close(); err != nil {
+			log.Errorf("job %s failed to close its sink: %v", job.ID, err)
+		}
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if job.Status != JobCancelled {
+		job.Status = JobDone
+	}
+	job.Seen = crawler.Seen()
+	job.result = result
+}
+
+// readyTimeout is the longest Ready's checks may take, within Kubernetes' default probe timeout of a second
+const readyTimeout = 800 * time.Millisecond
+
+// Ready reports whether another job could be queued and run, with the outcome of each check it made by name
+func (m *JobManager) Ready(ctx context.Context) (bool, map[string]string) {
+	ready, checks := true, map[string]string{"queue": "ok"}
+	if waiting := len(m.queue); waiting >= cap(m.queue) {
+		ready, checks["queue"] = false, fmt.Sprintf("full, with %d jobs waiting", waiting)
+	}
+	if bodies := m.Settings().Bodies; bodies != nil {
+		ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+		defer cancel()
+		checks["bodies"] = "ok"
+		if err := bodies.Ping(ctx); err != nil {
+			ready, checks["bodies"] = false, err.Error()
+		}
+	}
+	return ready, checks
+}
+
+// Settings returns the settings jobs started from now on would run with
+func (m *JobManager) Settings() JobSettings {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.settings
+}
+
+// Reconfigure has jobs started from now on run with settings, leaving those already running as they are
+func (m *JobManager) Reconfigure(settings JobSettings) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.settings = settings
+}
+
+// Counts returns how many jobs are in each status
+func (m *JobManager) Counts() map[string]int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	counts := make(map[string]int)
+	for _, job := range m.jobs {
+		counts[job.Status]++
+	}
+	return counts
+}
+
+// Submit queues a crawl of an already validated request, returning a copy of the job as it was queued, failing if the
+// queue is already full
+func (m *JobManager) Submit(seed *url.URL, req crawlRequest) (Job, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nextID++
+	job := &Job{
+		ID:                strconv.Itoa(m.nextID),
+		Seed:              seed.String(),
+		Depth:             req.Depth,
+		Scope:             req.Scope,
+		MaxPages:          req.MaxPages,
+		Sink:              req.Sink,
+		Email:             req.Email,
+		FoldTrailingSlash: req.FoldTrailingSlash,
+		Status:            JobQueued,
+
+		Created: time.Now(),
+		seed:    seed,
+		updated: make(chan struct{}),
+	}
+	select {
+	case m.queue <- job:
+	default:
+		return Job{}, false
+	}
+	m.jobs[job.ID] = job
+	return *job, true //copied under the mutex, as run may already be updating it
+}
+
+// Get returns a copy of a job, so it can be read without holding the lock
+func (m *JobManager) Get(id string) (Job, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns a copy of every job, oldest first
+func (m *JobManager) List() []Job {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.Before(jobs[j].Created) })
+	return jobs
+}
+
+// Cancel stops a queued or running job, returning its state afterwards.
+// A running job reports cancelled straight away but its result only settles once in-flight fetches return.
+func (m *JobManager) Cancel(id string) (Job, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	switch job.Status {
+	case JobQueued:
+		job.Status = JobCancelled
+		job.Finished = time.Now()
+		job.stopped = true
+	case JobRunning:
+		job.Status = JobCancelled
+		job.cancel()
+	}
+	job.notify()
+	return *job, true
+}
+
+// Pages returns the job's fetched pages from index from onwards, whether the job has stopped,
+// and a channel that is closed when there is something new to look at
+func (m *JobManager) Pages(id string, from int) ([]*Page, bool, <-chan struct{}, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false, nil, false
+	}
+	var pages []*Page
+	if from < len(job.pages) {
+		pages = job.pages[from:len(job.pages):len(job.pages)]
+	}
+	return pages, job.stopped, job.updated, true
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/abadojack/whatlanggo"
+	"strings"
+)
+
+// languageSampleWords is how much of a page's text language detection looks at, which is plenty to tell
+const languageSampleWords = 1000
+
+// detectLanguage guesses the ISO 639-1 code of the language the words are in, or ISO 639-3 where there isn't one.
+// It returns "" if there is too little text for a reliable guess.
+func detectLanguage(words []string) string {
+	if len(words) > languageSampleWords {
+		words = words[:languageSampleWords]
+	}
+	info := whatlanggo.Detect(strings.Join(words, " "))
+	if !info.IsReliable() {
+		return ""
+	}
+	if code := info.Lang.Iso6391(); code != "" {
+		return code
+	}
+	return info.Lang.Iso6393()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/op/go-logging"
+	"io"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// logFlags say how much every command logs and how
+type logFlags struct {
+	level, format                  string
+	verbose, veryVerbose, noColour bool
+}
+
+func (f *logFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.level, "log-level", "info", "Log this level and above: critical, error, warning, notice, info or debug")
+	flags.StringVar(&f.format, "log-format", "text", "Log as text or as json, one object per line")
+	flags.BoolVar(&f.verbose, "v", false, "Log at debug level, including every fetch and link not followed")
+	flags.BoolVar(&f.veryVerbose, "vv", false, "Log at debug level, with where in the code each line came from")
+	flags.BoolVar(&f.noColour, "no-color", false, "Don't colour the webmap, as it is when not written to a terminal or NO_COLOR is set")
+}
+
+// apply sets up the logger, replacing the default of text at every level
+func (f *logFlags) apply() error {
+	level, err := logging.LogLevel(f.level)
+	if err != nil {
+		return fmt.Errorf("unknown log level %s", f.level)
+	}
+	if f.verbose || f.veryVerbose {
+		level = logging.DEBUG
+	}
+	noColour = noColour || f.noColour || f.format == "json"
+	var backend logging.Backend
+	switch f.format {
+	case "text":
+		format := "%{message}"
+		if f.veryVerbose {
+			format = "%{level:.4s} %{shortfile} %{message}"
+		}
+		backend = logging.NewBackendFormatter(logging.NewLogBackend(os.Stderr, "", stdlog.LstdFlags), logging.MustStringFormatter(format))
+	case "json":
+		backend = logging.NewBackendFormatter(logging.NewLogBackend(os.Stderr, "", 0), jsonFormatter{caller: f.veryVerbose})
+	default:
+		return fmt.Errorf("unknown log format %s", f.format)
+	}
+	leveled := logging.AddModuleLevel(backend)
+	leveled.SetLevel(level, "")
+	logging.SetBackend(leveled)
+	return nil
+}
+
+// jsonFormatter writes each log record as a JSON object, for log collectors
+type jsonFormatter struct {
+	caller bool //whether to say which file and line logged it
+}
+
+func (f jsonFormatter) Format(calldepth int, r *logging.Record, w io.Writer) error {
+	record := struct {
+		Time    time.Time `json:"time"`
+		Level   string    `json:"level"`
+		Message string    `json:"message"`
+		Caller  string    `json:"caller,omitempty"`
+	}{Time: r.Time, Level: strings.ToLower(r.Level.String()), Message: r.Message()}
+	if _, file, line, ok := runtime.Caller(calldepth + 1); f.caller && ok {
+		record.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// LoginOptions describe a form login performed before crawling, so the client's cookie jar holds a session
+type LoginOptions struct {
+	URL     string            //page with the login form on it
+	Fields  map[string]string //values to fill in, on top of whatever the form already has, like CSRF tokens
+	Success *regexp.Regexp    //must match the response to the submitted form for the login to count
+}
+
+// loginForm is the parts of a form needed to submit it
+type loginForm struct {
+	method      string
+	action      string
+	values      url.Values
+	hasPassword bool
+}
+
+// login fetches the form page, fills in the form that has a password field (or the first form) and submits it
+func login(ctx context.Context, client *http.Client, opts LoginOptions) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", opts.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	forms, err := parseForms(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if len(forms) == 0 {
+		return fmt.Errorf("no form found on %s", opts.URL)
+	}
+	form := forms[0]
+	for _, f := range forms {
+		if f.hasPassword {
+			form = f
+			break
+		}
+	}
+	for name, value := range opts.Fields {
+		form.values.Set(name, value)
+	}
+	action, err := resp.Request.URL.Parse(form.action) //relative to wherever the form page ended up after redirects
+	if err != nil {
+		return fmt.Errorf("couldn't parse form action %q: %v", form.action, err)
+	}
+	if form.method == "GET" {
+		action.RawQuery = form.values.Encode()
+		req, err = http.NewRequestWithContext(ctx, "GET", action.String(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, "POST", action.String(), strings.NewReader(form.values.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login form submission got %s", resp.Status)
+	}
+	if opts.Success != nil {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !opts.Success.Match(body) && !opts.Success.MatchString(resp.Request.URL.String()) {
+			return fmt.Errorf("login response from %s didn't match %s", resp.Request.URL.String(), opts.Success.String())
+		}
+	}
+	log.Infof("logged in through %s", opts.URL)
+	return nil
+}
+
+// parseForms collects every form on a page along with the values its inputs would submit
+func parseForms(r io.Reader) ([]*loginForm, error) {
+	var forms []*loginForm
+	var current *loginForm
+	tokens := html.NewTokenizer(r)
+	for {
+		tokenType := tokens.Next()
+		if tokenType == html.ErrorToken {
+			if tokens.Err() == io.EOF {
+				return forms, nil
+			}
+			return nil, tokens.Err()
+		}
+		token := tokens.Token()
+		switch {
+		case tokenType == html.EndTagToken && token.DataAtom == atom.Form:
+			current = nil
+		case tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken:
+		case token.DataAtom == atom.Form:
+			current = &loginForm{method: strings.ToUpper(attrValue(token, "method")), action: attrValue(token, "action"), values: url.Values{}}
+			forms = append(forms, current)
+		case token.DataAtom == atom.Input && current != nil:
+			name, inputType := attrValue(token, "name"), strings.ToLower(attrValue(token, "type"))
+			if inputType == "password" {
+				current.hasPassword = true
+			}
+			if name == "" || inputType == "submit" || inputType == "button" || inputType == "image" {
+				continue
+			}
+			if (inputType == "checkbox" || inputType == "radio") && !hasAttr(token, "checked") {
+				continue
+			}
+			current.values.Add(name, attrValue(token, "value"))
+		}
+	}
+}
+
+func attrValue(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(token html.Token, key string) bool {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
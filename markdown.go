@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/go-shiori/go-readability"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// saveMarkdown converts a page's main content to Markdown, headed by its title, and saves it as dir/host/path.md
+func saveMarkdown(dir string, target *Page, body []byte) error {
+	article, err := readability.FromReader(bytes.NewReader(body), (*target).URL)
+	if err != nil || article.Node == nil {
+		return err
+	}
+	content, err := htmltomarkdown.ConvertNode(article.Node, converter.WithDomain((*target).URL.String())) //links absolute, as they'd break relative to the .md
+	if err != nil {
+		return err
+	}
+	var markdown bytes.Buffer
+	if title := strings.TrimSpace((*target).Title); title != "" && !bytes.HasPrefix(content, []byte("# ")) {
+		markdown.WriteString("# " + title + "\n\n")
+	}
+	markdown.Write(content)
+	markdown.WriteString("\n")
+	local := localPath(dir, (*target).URL, true)
+	local = strings.TrimSuffix(local, filepath.Ext(local)) + ".md"
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(local, markdown.Bytes(), 0644)
+}
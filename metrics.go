@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pushTimeout is the longest pushing a crawl's metrics may take, so a missing gateway, server or webhook can't hang a batch run
+const pushTimeout = 30 * time.Second
+
+// crawlMetric is one number about a finished crawl, for monitoring to graph
+type crawlMetric struct {
+	Name  string //snake case, without a prefix
+	Value float64
+}
+
+// crawlMetrics measures a finished crawl: how long it took, and each of gateMetrics over the pages under roots
+func crawlMetrics(roots []*Page, elapsed time.Duration) []crawlMetric {
+	pages := graphPages(roots)
+	metrics := []crawlMetric{{"duration_seconds", elapsed.Seconds()}}
+	for _, name := range gateMetricNames() {
+		metrics = append(metrics, crawlMetric{strings.ReplaceAll(name, "-", "_"), float64(gateMetrics[name](pages))})
+	}
+	return metrics
+}
+
+// pushMetrics replaces whatever job last pushed to the Prometheus Pushgateway at gateway with metrics, as gauges named
+// monzo_crawl_*, along with when they were pushed
+func pushMetrics(gateway, job string, metrics []crawlMetric) error {
+	var body strings.Builder
+	pushed := crawlMetric{"last_push_timestamp_seconds", float64(time.Now().Unix())}
+	for _, m := range append(metrics[:len(metrics):len(metrics)], pushed) {
+		fmt.Fprintf(&body, "# TYPE monzo_crawl_%s gauge\nmonzo_crawl_%s %g\n", m.Name, m.Name, m.Value)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	endpoint := strings.TrimRight(gateway, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, strings.NewReader(body.String()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("pushgateway responded %s", resp.Status)
+	}
+	return nil
+}
+
+// sendStatsD sends metrics as gauges named monzo.crawl.* to the StatsD server at addr, with tags like env:ci in the
+// DogStatsD way if there are any
+func sendStatsD(addr string, tags []string, metrics []crawlMetric) error {
+	conn, err := net.DialTimeout("udp", addr, pushTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	suffix := ""
+	if len(tags) > 0 {
+		suffix = "|#" + strings.Join(tags, ",")
+	}
+	for _, m := range metrics { //a packet each, so none outgrows the network's MTU
+		if _, err := fmt.Fprintf(conn, "monzo.crawl.%s:%g|g%s", m.Name, m.Value, suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportMetrics sends metrics to whichever of a Pushgateway and a StatsD server are set, logging how that went
+func reportMetrics(metrics []crawlMetric, pushGateway, pushJob, statsdAddr, statsdTags string) {
+	if pushGateway != "" {
+		if err := pushMetrics(pushGateway, pushJob, metrics); err != nil {
+			log.Error("couldn't push metrics:", err)
+		} else {
+			log.Info("Pushed metrics to", pushGateway)
+		}
+	}
+	if statsdAddr != "" {
+		if err := sendStatsD(statsdAddr, splitList(statsdTags), metrics); err != nil {
+			log.Error("couldn't send metrics to statsd:", err)
+		} else {
+			log.Info("Sent metrics to", statsdAddr)
+		}
+	}
+}
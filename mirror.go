@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// mirrorAttrs are the attributes holding URLs that a mirror rewrites to point at its local copies
+var mirrorAttrs = map[string]bool{"href": true, "src": true}
+
+// Mirror saves a crawl to disk laid out like the sites' URLs, wget -m style, so it can be browsed offline.
+// Pages are saved as they are fetched, then Finish rewrites their links once it is known which URLs were saved.
+type Mirror struct {
+	Dir     string
+	Statics bool //also save every static the saved pages refer to
+
+	mutex sync.Mutex
+	files map[string]string   //local path of each saved URL
+	pages map[string]*url.URL //the URL each saved HTML file was fetched from, for resolving its links
+	once  map[string]*sync.Once
+}
+
+func NewMirror(dir string, statics bool) *Mirror {
+	return &Mirror{Dir: dir, Statics: statics, files: make(map[string]string), pages: make(map[string]*url.URL), once: make(map[string]*sync.Once)}
+}
+
+// localPath is where a URL is saved under dir: dir/host/path, with index.html for directories, the query folded into the name,
+// and .html added to HTML pages without an HTML extension so browsers open them as pages
+func localPath(dir string, u *url.URL, isHTML bool) string {
+	p := u.EscapedPath()
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	if u.RawQuery != "" {
+		p += "@" + u.RawQuery
+	}
+	if ext := strings.ToLower(path.Ext(p)); isHTML && ext != ".html" && ext != ".htm" {
+		p += ".html"
+	}
+	p = strings.Map(func(r rune) rune {
+		if r == ':' || r == '\\' || r == '?' || r == '*' || r == '"' || r == '<' || r == '>' || r == '|' {
+			return '_'
+		}
+		return r
+	}, p)
+	return filepath.Join(dir, strings.ReplaceAll(u.Host, ":", "_"), filepath.FromSlash(path.Clean("/"+p)))
+}
+
+func (m *Mirror) save(u *url.URL, isHTML bool, body io.Reader) error {
+	local := localPath(m.Dir, u, isHTML)
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(local)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.files[u.String()] = local
+	if isHTML {
+		m.pages[local] = u
+	}
+	return nil
+}
+
+// savePage saves a successfully fetched page as it came, links untouched until Finish
+func (m *Mirror) savePage(target *Page, body []byte) {
+	if (*target).Status != http.StatusOK {
+		return
+	}
+	if err := m.save((*target).URL, (*target).isHTML(), bytes.NewReader(body)); err != nil {
+		log.Errorf("failed to mirror %s: %v", (*target).URL.String(), err)
+	}
+}
+
+// saveStatics fetches and saves each of a page's statics, once per mirror however many pages refer to them
+func (m *Mirror) saveStatics(ctx context.Context, c *Crawler, target *Page) {
+	for _, static := range (*target).Statics {
+		m.mutex.Lock()
+		once, ok := m.once[static.String()]
+		if !ok {
+			once = &sync.Once{}
+			m.once[static.String()] = once
+		}
+		m.mutex.Unlock()
+		once.Do(func() {
+			if err := m.saveStatic(ctx, c, static); err != nil {
+				log.Warningf("failed to mirror static %s: %v", static.String(), err)
+			}
+		})
+	}
+}
+
+func (m *Mirror) saveStatic(ctx context.Context, c *Crawler, u *url.URL) error {
+	release, ok := c.acquireHost(ctx, u)
+	if !ok {
+		return ctx.Err()
+	}
+	defer release()
+	req, err := c.newRequest(ctx, "GET", u.String())
+	if err != nil {
+		return err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return m.save(u, false, resp.Body)
+}
+
+// Finish rewrites the links in every saved page to relative paths where the URL was saved too, and to absolute URLs where it wasn't
+func (m *Mirror) Finish() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for local, pageURL := range m.pages {
+		if err := m.rewrite(local, pageURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mirror) rewrite(local string, pageURL *url.URL) error {
+	original, err := os.ReadFile(local)
+	if err != nil {
+		return err
+	}
+	var rewritten bytes.Buffer
+	tokens := html.NewTokenizer(bytes.NewReader(original))
+	for {
+		tokenType := tokens.Next()
+		if tokenType == html.ErrorToken {
+			if tokens.Err() != io.EOF {
+				return tokens.Err()
+			}
+			break
+		}
+		raw := tokens.Raw()
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			rewritten.Write(raw)
+			continue
+		}
+		rawCopy := append([]byte{}, raw...) //Token reuses the tokenizer's buffer
+		token := tokens.Token()
+		changed := false
+		for i, attr := range token.Attr {
+			if !mirrorAttrs[attr.Key] {
+				continue
+			}
+			if link, ok := m.localLink(local, pageURL, attr.Val); ok {
+				token.Attr[i].Val, changed = link, true
+			}
+		}
+		if changed {
+			rewritten.WriteString(token.String())
+		} else {
+			rewritten.Write(rawCopy)
+		}
+	}
+	return os.WriteFile(local, rewritten.Bytes(), 0644)
+}
+
+// localLink is what a link on the page saved at local should become, false to leave it be
+func (m *Mirror) localLink(local string, pageURL *url.URL, href string) (string, bool) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || ref.Scheme == "mailto" || ref.Scheme == "tel" || ref.Scheme == "javascript" || ref.Scheme == "data" {
+		return "", false
+	}
+	if ref.Scheme == "" && ref.Host == "" && ref.Path == "" && ref.RawQuery == "" { //just a fragment, already local
+		return "", false
+	}
+	target := pageURL.ResolveReference(ref)
+	fragment := target.Fragment
+	target.Fragment = ""
+	saved, ok := m.files[target.String()]
+	if !ok {
+		target.Fragment = fragment
+		return target.String(), true
+	}
+	rel, err := filepath.Rel(filepath.Dir(local), saved)
+	if err != nil {
+		return "", false
+	}
+	link := (&url.URL{Path: filepath.ToSlash(rel)}).String()
+	if fragment != "" {
+		link += "#" + url.PathEscape(fragment)
+	}
+	return link, true
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"golang.org/x/net/idna"
+	"net/url"
+	"strings"
+)
+
+// defaultPorts are left out of normalised URLs, being what the scheme implies anyway
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// normalise rewrites u into the one spelling of it the crawl dedups on, changing nothing a server could tell apart:
+// the scheme and host are lower cased, a default or empty port dropped, dot segments removed, percent-encoding
+// upper cased with unreserved characters decoded, an empty path made /, and a unicode host put in punycode. With FoldTrailingSlash, /docs/ becomes
+// /docs too, which most servers treat the same but some don't.
+func (c *Crawler) normalise(u *url.URL) *url.URL {
+	return spellURL(u, c.FoldTrailingSlash)
+}
+
+// spellURL is normalise for whoever needs the crawl's spelling of a URL without a Crawler, like a Coordinator
+// finding a worker's seed among its results
+func spellURL(u *url.URL, foldTrailingSlash bool) *url.URL {
+	n := *u
+	n.Scheme, n.Host = strings.ToLower(n.Scheme), strings.ToLower(n.Host)
+	if port := n.Port(); port == "" && strings.HasSuffix(n.Host, ":") || port != "" && port == defaultPorts[n.Scheme] {
+		n.Host = strings.TrimSuffix(n.Host[:len(n.Host)-len(port)], ":")
+	}
+	n.Host = asciiHost(n.Host)
+	if n.Opaque != "" || n.Host == "" {
+		return &n
+	}
+	escaped := removeDotSegments(normaliseEscapes(n.EscapedPath()))
+	if escaped == "" {
+		escaped = "/"
+	}
+	if foldTrailingSlash && len(escaped) > 1 {
+		escaped = strings.TrimSuffix(escaped, "/")
+	}
+	if unescaped, err := url.PathUnescape(escaped); err == nil {
+		n.Path, n.RawPath = unescaped, escaped
+	}
+	n.RawQuery = normaliseEscapes(n.RawQuery)
+	return &n
+}
+
+// normaliseSeeds puts the seeds in the spelling their links will be normalised to, for scoping to and dedup against
+func (c *Crawler) normaliseSeeds() {
+	seeds := make([]*url.URL, len(c.Seeds))
+	for i, seed := range c.Seeds {
+		seeds[i] = c.normalise(seed)
+	}
+	c.Seeds = seeds
+}
+
+// asciiHost puts a host with a port or without in punycode if it's an internationalised domain name, as it needs to be
+// for looking up, leaving it as it is if it's already ASCII or isn't a valid IDN
+func asciiHost(host string) string {
+	if isASCII(host) {
+		return host
+	}
+	name, port := host, ""
+	if i := strings.LastIndex(host, ":"); i >= 0 { //an IPv6 address would be ASCII
+		name, port = host[:i], host[i:]
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return host
+	}
+	return ascii + port
+}
+
+// displayURL is a URL for people to read, with any punycode host in unicode
+func displayURL(rawURL string) string {
+	if !strings.Contains(rawURL, "xn--") {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	prefix := u.Scheme + "://" + u.Host
+	name, err := idna.Display.ToUnicode(u.Hostname())
+	if err != nil || !strings.HasPrefix(rawURL, prefix) {
+		return rawURL
+	}
+	if port := u.Port(); port != "" {
+		name += ":" + port
+	}
+	return u.Scheme + "://" + name + rawURL[len(prefix):] //the rest as it was spelled, escapes and all
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// removeDotSegments resolves the . and .. segments of an absolute path, as RFC 3986 does
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	kept := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+		case "..":
+			if len(kept) > 1 { //the first is the empty one before the leading slash
+				kept = kept[:len(kept)-1]
+			}
+		default:
+			kept = append(kept, segment)
+			continue
+		}
+		if last { //so the path still ends in a slash
+			kept = append(kept, "")
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// normaliseEscapes upper cases the hex of each percent escape in s, decoding those of unreserved characters, which
+// mean the same either way
+func normaliseEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			b.WriteByte(decoded)
+		} else {
+			b.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	}
+	return c - 'a' + 10
+}
+
+// isUnreserved reports whether RFC 3986 lets c appear in a URL unescaped wherever it is
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
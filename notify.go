@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxNotifyLinks is how many new broken links a notification lists before just counting the rest, as Discord caps a
+// message at 2000 characters
+const maxNotifyLinks = 10
+
+// notifySummary describes a finished crawl for a chat message: its totals, the links broken since the crawl it was
+// compared with if there was one, and where to read the full report if that's known
+func notifySummary(roots []*Page, elapsed time.Duration, broken []string, compared bool, reportURL string) string {
+	pages := graphPages(roots)
+	var seeds []string
+	for _, root := range roots {
+		seeds = append(seeds, displayURL(root.URL.String()))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Crawl of %s finished in %s: %d pages, %d errors, %d broken links\n", strings.Join(seeds, ", "),
+		elapsed.Round(time.Millisecond), gateMetrics["pages"](pages), gateMetrics["errors"](pages), gateMetrics["broken-links"](pages))
+	if compared {
+		fmt.Fprintf(&b, "New broken links since the last crawl: %d\n", len(broken))
+		for i, line := range broken {
+			if i == maxNotifyLinks {
+				fmt.Fprintf(&b, "    and %d more\n", len(broken)-i)
+				break
+			}
+			fmt.Fprintln(&b, "    "+line)
+		}
+	}
+	if reportURL != "" {
+		fmt.Fprintln(&b, "Report:", reportURL)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// notify posts text to a Slack or Discord webhook, telling which by the webhook's host
+func notify(webhook, text string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return err
+	}
+	message := map[string]string{"text": text} //Slack's incoming webhooks, and most that copy them
+	if host := u.Hostname(); host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+		message = map[string]string{"content": text}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// pagesByURL indexes every page under roots by its URL, as loadCrawl does a crawl it reads
+func pagesByURL(roots []*Page) map[string]*Page {
+	pages := graphPages(roots)
+	byURL := make(map[string]*Page, len(pages))
+	for _, page := range pages {
+		byURL[page.URL.String()] = page
+	}
+	return byURL
+}
+
+// Announcement says where a finished crawl's summary goes
+type Announcement struct {
+	Webhook         string   //Slack or Discord webhook to post it to
+	Mailer          *Mailer  //what mails it to MailTo
+	MailTo          []string //addresses to mail it to
+	SincePath       string   //an earlier crawl to list the links broken since, if set
+	RegressionsOnly bool     //only send it if links broke since SincePath
+	ReportURL       string   //where the full report is, to link to
+}
+
+// announceCrawl sends notifySummary wherever a says
+func announceCrawl(a Announcement, roots []*Page, elapsed time.Duration) {
+	var broken []string
+	if a.SincePath != "" {
+		_, before, err := loadCrawl(a.SincePath)
+		if err != nil {
+			log.Error("couldn't read earlier crawl to announce regressions:", err)
+			return
+		}
+		broken = newBrokenLinks(before, pagesByURL(roots))
+	}
+	if a.RegressionsOnly && len(broken) == 0 {
+		return
+	}
+	summary := notifySummary(roots, elapsed, broken, a.SincePath != "", a.ReportURL)
+	if a.Webhook != "" {
+		if err := notify(a.Webhook, summary); err != nil {
+			log.Error("couldn't notify:", err)
+		} else {
+			log.Info("Sent the crawl's summary to the webhook") //whose URL is its secret, so isn't logged
+		}
+	}
+	if a.Mailer != nil && len(a.MailTo) > 0 {
+		if err := a.Mailer.Send(a.MailTo, mailSubject(summary), summary); err != nil {
+			log.Error("couldn't mail the crawl's summary:", err)
+		} else {
+			log.Info("Mailed the crawl's summary to", strings.Join(a.MailTo, ", "))
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// formats maps an output format name to the function that writes a crawled site map in it
+var formats = map[string]func(io.Writer, *Page) error{
+	"text":     writeText,
+	"json":     writeJSON,
+	"ndjson":   writeNDJSON,
+	"hreflang": writeHreflang,
+	"grep":     writeGrep,
+	"contacts": writeContacts,
+	"dot":      writeDOT,
+	"graphml":  writeGraphML,
+	"sitemap":  writeSitemap,
+	"csv":      writeCSV,
+	"github":   writeGitHub,
+	"parquet":  writeParquet,
+	"html":     writeHTMLReport,
+	"warc":     writeWARC,
+}
+
+// formatExtensions maps the file extensions -o recognises to the format they are written in
+var formatExtensions = map[string]string{
+	".txt":     "text",
+	".json":    "json",
+	".ndjson":  "ndjson",
+	".dot":     "dot",
+	".graphml": "graphml",
+	".xml":     "sitemap",
+	".csv":     "csv",
+	".parquet": "parquet",
+	".html":    "html",
+	".warc":    "warc",
+}
+
+// extensionNames lists the extensions -o recognises, for its help
+func extensionNames() []string {
+	names := make([]string, 0, len(formatExtensions))
+	for ext := range formatExtensions {
+		names = append(names, ext)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// webmapFormats are the formats needing every root in one document, with one header row or root element, rather than
+// each root's written after the last's
+var webmapFormats = map[string]func(io.Writer, []*Page) error{
+	"csv":     writeCSVs,
+	"sitemap": writeSitemaps,
+	"graphml": writeGraphMLs,
+	"parquet": writeParquets,
+	"html":    writeHTMLReports,
+	"warc":    writeWARCs,
+}
+
+// webmapWriter writes every root with write, as the one document format needs if it's one of webmapFormats
+func webmapWriter(format string, write func(io.Writer, *Page) error) func(io.Writer, []*Page) error {
+	if writeAll, ok := webmapFormats[format]; ok {
+		return writeAll
+	}
+	return func(w io.Writer, roots []*Page) error {
+		for _, root := range roots {
+			if err := write(w, root); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// formatNames lists the supported output formats, for flag help and error messages
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarshalJSON writes a Page in the nested form returned by the json format
+func (p *Page) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.jsonTree(make(map[*Page]struct{})))
+}
+
+// jsonTree nests each page's links inside it, giving pages linked to more than once in full only the first time and as just their URL after
+func (p *Page) jsonTree(seen map[*Page]struct{}) any {
+	if _, ok := seen[p]; ok {
+		return struct {
+			URL string `json:"url"`
+		}{p.URL.String()}
+	}
+	seen[p] = struct{}{}
+	statics := make([]string, len(p.Statics))
+	for i, static := range p.Statics {
+		statics[i] = static.String()
+	}
+	links := make([]any, len(p.Links))
+	for i, link := range p.Links {
+		links[i] = link.jsonTree(seen)
+	}
+	var fetched *time.Time //unset for pages that were never fetched
+	if !p.Fetched.IsZero() {
+		fetched = &p.Fetched
+	}
+	return struct {
+		URL                 string            `json:"url"`
+		Status              int               `json:"status,omitempty"`
+		Fetched             *time.Time        `json:"fetched,omitempty"`
+		ContentType         string            `json:"content_type,omitempty"`
+		LatencyMS           float64           `json:"latency_ms,omitempty"`
+		Depth               int               `json:"depth,omitempty"`
+		Referrer            string            `json:"referrer,omitempty"`
+		Fallback            string            `json:"fallback,omitempty"`
+		Redirects           []Redirect        `json:"redirects,omitempty"`
+		SecurityHeaders     map[string]string `json:"security_headers,omitempty"`
+		Size                int64             `json:"size,omitempty"`
+		ContentHash         string            `json:"content_hash,omitempty"`
+		Truncated           bool              `json:"truncated,omitempty"`
+		BodyKey             string            `json:"body_key,omitempty"`
+		Fingerprint         string            `json:"fingerprint,omitempty"`
+		Changed             *bool             `json:"changed,omitempty"`
+		Partial             bool              `json:"partial,omitempty"`
+		PageRank            float64           `json:"pagerank,omitempty"`
+		Hub                 float64           `json:"hub,omitempty"`
+		Authority           float64           `json:"authority,omitempty"`
+		ClickDepth          int               `json:"click_depth,omitempty"`
+		Structure           *SiteStats        `json:"structure,omitempty"`
+		Canonical           string            `json:"canonical,omitempty"`
+		Sitemap             []string          `json:"sitemap,omitempty"`
+		Error               string            `json:"error,omitempty"`
+		TLSError            string            `json:"tls_error,omitempty"`
+		TLS                 *TLSInfo          `json:"tls,omitempty"`
+		UserAgent           string            `json:"user_agent,omitempty"`
+		Title               string            `json:"title,omitempty"`
+		Description         string            `json:"description,omitempty"`
+		Keywords            []string          `json:"keywords,omitempty"`
+		Robots              []string          `json:"robots,omitempty"`
+		H1s                 []string          `json:"h1s,omitempty"`
+		Text                string            `json:"text,omitempty"`
+		MainText            string            `json:"main_text,omitempty"`
+		WordCount           int               `json:"word_count,omitempty"`
+		Relevance           float64           `json:"relevance,omitempty"`
+		EmptyLinks          int               `json:"empty_links,omitempty"`
+		Aliases             []string          `json:"aliases,omitempty"`
+		Matches             []GrepMatch       `json:"matches,omitempty"`
+		Emails              []string          `json:"emails,omitempty"`
+		Phones              []string          `json:"phones,omitempty"`
+		Lang                string            `json:"lang,omitempty"`
+		DetectedLang        string            `json:"detected_lang,omitempty"`
+		StructuredData      []any             `json:"structured_data,omitempty"`
+		Fields              map[string]any    `json:"fields,omitempty"`
+		Statics             []string          `json:"statics"`
+		MixedContent        []string          `json:"mixed_content,omitempty"`
+		AccessibilityIssues []string          `json:"accessibility_issues,omitempty"`
+		Assets              []*Asset          `json:"assets,omitempty"`
+		ExternalLinks       []string          `json:"external_links,omitempty"`
+		External            []*Asset          `json:"external,omitempty"`
+		Links               []any             `json:"links"`
+		Anchors             []*Anchor         `json:"anchors,omitempty"`
+		Alternates          []*Alternate      `json:"alternates,omitempty"`
+	}{
+		URL:                 p.URL.String(),
+		Status:              p.Status,
+		Fetched:             fetched,
+		ContentType:         p.ContentType,
+		LatencyMS:           milliseconds(p.Latency),
+		Depth:               p.Depth,
+		Referrer:            p.Referrer,
+		Fallback:            p.Fallback,
+		Redirects:           p.Redirects,
+		SecurityHeaders:     p.SecurityHeaders,
+		Size:                p.Size,
+		ContentHash:         p.ContentHash,
+		Truncated:           p.Truncated,
+		BodyKey:             p.BodyKey,
+		Fingerprint:         p.Fingerprint,
+		Changed:             p.Changed,
+		Partial:             p.Partial,
+		PageRank:            p.PageRank,
+		Hub:                 p.Hub,
+		Authority:           p.Authority,
+		ClickDepth:          p.ClickDepth,
+		Structure:           p.Structure,
+		Canonical:           p.Canonical,
+		Sitemap:             p.Sitemap,
+		Error:               p.Error,
+		TLSError:            p.TLSError,
+		TLS:                 p.TLS,
+		UserAgent:           p.UserAgent,
+		Title:               p.Title,
+		Description:         p.Description,
+		Keywords:            p.Keywords,
+		Robots:              p.Robots,
+		H1s:                 p.H1s,
+		Text:                p.Text,
+		MainText:            p.MainText,
+		WordCount:           p.WordCount,
+		Relevance:           p.Relevance,
+		EmptyLinks:          p.EmptyLinks,
+		Aliases:             p.Aliases,
+		Matches:             p.Matches,
+		Emails:              p.Emails,
+		Phones:              p.Phones,
+		Lang:                p.Lang,
+		DetectedLang:        p.DetectedLang,
+		StructuredData:      p.StructuredData,
+		Fields:              p.Fields,
+		Statics:             statics,
+		MixedContent:        p.MixedContent,
+		AccessibilityIssues: p.AccessibilityIssues,
+		Assets:              p.Assets,
+		ExternalLinks:       urlStrings(p.ExternalLinks),
+		External:            p.External,
+		Links:               links,
+		Anchors:             p.Anchors,
+		Alternates:          p.Alternates,
+	}
+}
+
+// urlStrings formats URLs for output, nil for none so they can be omitted
+func urlStrings(urls []*url.URL) []string {
+	var strs []string
+	for _, u := range urls {
+		strs = append(strs, u.String())
+	}
+	return strs
+}
+
+func writeJSON(w io.Writer, page *Page) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(page)
+}
+
+// writeNDJSON writes one flat record per page, parents before their links
+func writeNDJSON(w io.Writer, page *Page) error {
+	enc := json.NewEncoder(w)
+	seen := make(map[*Page]struct{})
+	var walk func(*Page) error
+	walk = func(page *Page) error {
+		if _, ok := seen[page]; ok {
+			return nil
+		}
+		seen[page] = struct{}{}
+		if err := enc.Encode(newPageRecord(page)); err != nil {
+			return err
+		}
+		for _, link := range page.Links {
+			if err := walk(link); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(page)
+}
+
+func writeText(w io.Writer, page *Page) error {
+	var err error
+	walkText(page, 0, colourful(w), func(line string) {
+		if err == nil {
+			_, err = fmt.Fprintln(w, line)
+		}
+	})
+	return err
+}
+
+// walkText produces the indented webmap one line at a time, listing pages linked to more than once in full only the first time.
+// Coloured, each line also gets the page's status, with the broken in red.
+func walkText(page *Page, indent int, colour bool, emit func(string)) {
+	seen := make(map[*Page]struct{})
+	label := func(s string) string {
+		if colour {
+			return paint(dim, s)
+		}
+		return s
+	}
+	var walk func(*Page, int)
+	walk = func(page *Page, indent int) {
+		_, repeated := seen[page]
+		line := displayURL((*page).URL.String())
+		if colour {
+			switch {
+			case repeated:
+				line = paint(dim, line)
+			case page.broken():
+				line = paint(red, line) + " " + paintStatus(page)
+			default:
+				line = paint(depthColours[indent/2%len(depthColours)], line) + " " + paintStatus(page)
+			}
+		}
+		emit(strings.Join([]string{strings.Repeat("    ", indent), line}, ""))
+		if repeated {
+			return
+		}
+		seen[page] = struct{}{}
+		if len((*page).Statics) > 0 {
+			emit(strings.Join([]string{strings.Repeat("    ", indent+1), label("Statics:")}, ""))
+			for _, static := range (*page).Statics {
+				emit(strings.Join([]string{strings.Repeat("    ", indent+2), displayURL((*static).String())}, ""))
+			}
+		}
+		if len((*page).Aliases) > 0 {
+			emit(strings.Join([]string{strings.Repeat("    ", indent+1), label("Aliases:")}, ""))
+			for _, alias := range (*page).Aliases {
+				emit(strings.Join([]string{strings.Repeat("    ", indent+2), displayURL(alias)}, ""))
+			}
+		}
+		if len((*page).Links) > 0 {
+			emit(strings.Join([]string{strings.Repeat("    ", indent+1), label("Links:")}, ""))
+			for _, subpage := range (*page).Links {
+				walk(subpage, indent+2)
+			}
+		}
+	}
+	walk(page, indent)
+}
+
+func printPage(page *Page, indent int) {
+	walkText(page, indent, colourful(os.Stderr), func(line string) { log.Info(line) })
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"time"
+)
+
+// parquetRowGroup is how many pages go in each of a Parquet file's row groups, so writing a huge crawl doesn't hold it
+// all in memory at once
+const parquetRowGroup = 100000
+
+// parquetRow is a page as a flat row, for loading into columnar stores like ClickHouse and BigQuery
+type parquetRow struct {
+	URL         string     `parquet:"url"`
+	Status      int32      `parquet:"status"`
+	Error       string     `parquet:"error,optional"`
+	ContentType string     `parquet:"content_type,optional"`
+	Size        int64      `parquet:"size"`
+	Title       string     `parquet:"title,optional"`
+	Description string     `parquet:"description,optional"`
+	Lang        string     `parquet:"lang,optional"`
+	Canonical   string     `parquet:"canonical,optional"`
+	ContentHash string     `parquet:"content_hash,optional"`
+	WordCount   int32      `parquet:"word_count"`
+	ClickDepth  int32      `parquet:"click_depth"`
+	Depth       int32      `parquet:"depth"`
+	Referrer    string     `parquet:"referrer,optional"`
+	Inlinks     int32      `parquet:"inlinks"`
+	Outlinks    int32      `parquet:"outlinks"`
+	PageRank    float64    `parquet:"pagerank"`
+	Fetched     *time.Time `parquet:"fetched,optional,timestamp(microsecond)"`
+	LatencyMS   float64    `parquet:"latency_ms"`
+	Links       []string   `parquet:"links,list"`
+	Statics     []string   `parquet:"statics,list"`
+}
+
+// writeParquet writes a row per page as Parquet, which ClickHouse's file() and INSERT ... FORMAT Parquet and BigQuery's
+// bq load --source_format=PARQUET all read as they are
+func writeParquet(w io.Writer, root *Page) error {
+	return writeParquets(w, []*Page{root})
+}
+
+// writeParquets is writeParquet for the pages under every root, through one writer so they make one file
+func writeParquets(w io.Writer, roots []*Page) error {
+	pages := graphPages(roots)
+	inlinks, outlinks := linkDegrees(pages)
+	writer := parquet.NewGenericWriter[parquetRow](w, parquet.Compression(&parquet.Zstd))
+	rows := make([]parquetRow, 0, min(len(pages), parquetRowGroup))
+	for _, page := range pages {
+		record := newPageRecord(page)
+		rows = append(rows, parquetRow{
+			URL:         record.URL,
+			Status:      int32(page.Status),
+			Error:       page.Error,
+			ContentType: page.ContentType,
+			Size:        page.Size,
+			Title:       page.Title,
+			Description: page.Description,
+			Lang:        page.Lang,
+			Canonical:   page.Canonical,
+			ContentHash: page.ContentHash,
+			WordCount:   int32(page.WordCount),
+			ClickDepth:  int32(page.ClickDepth),
+			Depth:       int32(page.Depth),
+			Referrer:    page.Referrer,
+			Inlinks:     int32(inlinks[page]),
+			Outlinks:    int32(outlinks[page]),
+			PageRank:    page.PageRank,
+			Fetched:     record.Fetched,
+			LatencyMS:   record.LatencyMS,
+			Links:       record.Links,
+			Statics:     record.Statics,
+		})
+		if len(rows) == parquetRowGroup {
+			if err := writeRowGroup(writer, rows); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+	}
+	if err := writeRowGroup(writer, rows); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func writeRowGroup(writer *parquet.GenericWriter[parquetRow], rows []parquetRow) error {
+	if _, err := writer.Write(rows); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPDFSize is the most of a PDF read looking for links, beyond which the rest is ignored
+const maxPDFSize = 32 << 20
+
+// pdfURI matches a link annotation's target, as a literal (string) or a <hex string>
+var pdfURI = regexp.MustCompile(`/URI\s*(\((?:\\.|[^\\)])*\)|<[0-9A-Fa-f\s]*>)`)
+
+// pdfStream matches each stream's data, which for object streams hides the annotations
+var pdfStream = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\nendstream`)
+
+func isPDF(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.EqualFold(strings.TrimSpace(mediaType), "application/pdf")
+}
+
+// pdfLinks finds the URIs a PDF's link annotations point at, looking inside its Flate compressed streams too
+func pdfLinks(body []byte) []string {
+	var links []string
+	seen := make(map[string]struct{})
+	var scan func([]byte, bool)
+	scan = func(data []byte, top bool) {
+		for _, match := range pdfURI.FindAllSubmatch(data, -1) {
+			link := strings.TrimSpace(pdfString(match[1]))
+			if _, ok := seen[link]; !ok && link != "" {
+				seen[link] = struct{}{}
+				links = append(links, link)
+			}
+		}
+		if !top {
+			return
+		}
+		for _, stream := range pdfStream.FindAllSubmatch(data, -1) {
+			r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+			if err != nil { //not Flate, so images and the like that won't hold links
+				continue
+			}
+			inflated, _ := io.ReadAll(io.LimitReader(r, maxPDFSize)) //what inflated before any error is still worth a look
+			scan(inflated, false)
+		}
+	}
+	scan(body, true)
+	return links
+}
+
+// pdfString decodes a PDF string object, either (literal) with backslash escapes or <hex>
+func pdfString(raw []byte) string {
+	if raw[0] == '<' {
+		digits := strings.Join(strings.Fields(string(raw[1:len(raw)-1])), "")
+		if len(digits)%2 == 1 { //a missing final digit is taken as 0
+			digits += "0"
+		}
+		decoded, err := hex.DecodeString(digits)
+		if err != nil {
+			return ""
+		}
+		return string(decoded)
+	}
+	literal := raw[1 : len(raw)-1]
+	var s strings.Builder
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' || i == len(literal)-1 {
+			s.WriteByte(literal[i])
+			continue
+		}
+		i++
+		switch c := literal[i]; c {
+		case 'n':
+			s.WriteByte('\n')
+		case 'r':
+			s.WriteByte('\r')
+		case 't':
+			s.WriteByte('\t')
+		case 'b':
+			s.WriteByte('\b')
+		case 'f':
+			s.WriteByte('\f')
+		case '\r', '\n': //a line continuation
+		default:
+			if c >= '0' && c <= '7' { //up to three octal digits
+				end := i + 1
+				for end < len(literal) && end < i+3 && literal[end] >= '0' && literal[end] <= '7' {
+					end++
+				}
+				code, _ := strconv.ParseUint(string(literal[i:end]), 8, 8)
+				s.WriteByte(byte(code))
+				i = end - 1
+			} else {
+				s.WriteByte(c)
+			}
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PriorityFunc scores a discovered URL, with the depth left to crawl below it, for ordering the frontier under StrategyPriority.
+// Higher scores are crawled sooner.
+type PriorityFunc func(u *url.URL, depth int) float64
+
+// PriorityRule weighs the URLs whose path and query match Pattern, for ordering the frontier under StrategyPriority
+type PriorityRule struct {
+	Pattern *regexp.Regexp
+	Weight  float64
+}
+
+// parsePriorityRules parses rules given as pattern=weight, like /product/=10 or ^/tag/=-5, the pattern being a regexp
+// and the weight split off at the last =
+func parsePriorityRules(specs []string) ([]PriorityRule, error) {
+	var rules []PriorityRule
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("priority rule %s isn't pattern=weight", spec)
+		}
+		pattern, err := regexp.Compile(spec[:i])
+		if err != nil {
+			return nil, fmt.Errorf("priority rule %s: %w", spec, err)
+		}
+		weight, err := strconv.ParseFloat(spec[i+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("priority rule %s has a weight that isn't a number", spec)
+		}
+		rules = append(rules, PriorityRule{Pattern: pattern, Weight: weight})
+	}
+	return rules, nil
+}
+
+// rulePriority scores URLs by urlPriority plus the weight of every rule they match, so rules outrank the path's segments
+// and ties between them still go to the shallower URL
+func rulePriority(rules []PriorityRule) PriorityFunc {
+	return func(u *url.URL, depth int) float64 {
+		score := urlPriority(u)
+		target := u.RequestURI()
+		for _, rule := range rules {
+			if rule.Pattern.MatchString(target) {
+				score += rule.Weight
+			}
+		}
+		return score
+	}
+}
+
+// priority scores a discovered URL with the crawl's PriorityFunc, or urlPriority without one
+func (c *Crawler) priority(u *url.URL, depth int) float64 {
+	if c.Priority != nil {
+		return c.Priority(u, depth)
+	}
+	return urlPriority(u)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// queueGroup is shared by every consuming process, so NATS hands each seed to only one of them
+const queueGroup = "monzo"
+
+// crawlSummary is the reply to a seed message that asked for one
+type crawlSummary struct {
+	Seed     string `json:"seed"`
+	Seen     int    `json:"seen"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// consumeQueue crawls seeds from a subject like nats://host:4222/crawl.seeds one at a time until ctx is done.
+// Messages are either a bare URL or a JSON crawl request, pages go to the sink and a summary goes to the message's reply subject if it has one.
+func consumeQueue(ctx context.Context, rawURL string, defaultDepth int, configure func(*Crawler), sink Sink) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "nats" {
+		return fmt.Errorf("unsupported queue %q, only nats:// is supported", rawURL)
+	}
+	server, subject, err := natsAddress(u)
+	if err != nil {
+		return err
+	}
+	conn, err := nats.Connect(server, nats.Name("monzo worker"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sub, err := conn.QueueSubscribeSync(subject, queueGroup)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	log.Infof("consuming seeds from %s on %s", subject, server)
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		summary := crawlQueued(ctx, msg.Data, defaultDepth, configure, sink)
+		if msg.Reply == "" {
+			continue
+		}
+		reply, err := json.Marshal(summary)
+		if err == nil {
+			err = conn.Publish(msg.Reply, reply)
+		}
+		if err != nil {
+			log.Errorf("failed to reply about %s: %v", summary.Seed, err)
+		}
+	}
+}
+
+func crawlQueued(ctx context.Context, data []byte, defaultDepth int, configure func(*Crawler), sink Sink) crawlSummary {
+	req := crawlRequest{Seed: strings.TrimSpace(string(data))}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if err := json.Unmarshal(data, &req); err != nil {
+			return crawlSummary{Error: "invalid crawl request: " + err.Error()}
+		}
+	}
+	if req.Depth <= 0 {
+		req.Depth = defaultDepth
+	}
+	if req.Scope == "" {
+		req.Scope = ScopeHost
+	}
+	summary := crawlSummary{Seed: req.Seed}
+	seed, err := url.Parse(req.Seed)
+	if err != nil || seed.Host == "" {
+		summary.Error = "seed must be an absolute URL"
+		return summary
+	}
+	if !validScope(req.Scope) {
+		summary.Error = "unknown scope " + req.Scope
+		return summary
+	}
+	start := time.Now()
+	crawler := NewCrawler([]*url.URL{seed}, req.Depth, req.Scope)
+	configure(crawler)
+	crawler.MaxPages = req.MaxPages
+	if sink != nil {
+		crawler.KeepText = true
+		crawler.OnPage = func(page *Page) {
+			if err := sink.Write(ctx, page); err != nil {
+				log.Errorf("failed to write %s to sink: %v", page.URL.String(), err)
+			}
+		}
+	}
+	crawler.Run(ctx)
+	summary.Seen = crawler.Seen()
+	summary.Duration = time.Since(start).String()
+	log.Infof("crawled %s from the queue, %d unique links in %s", summary.Seed, summary.Seen, summary.Duration)
+	return summary
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
+	"net/url"
+	"strings"
+)
+
+// extractMainText finds the page's main content the way browser reader modes do, leaving out navigation, footers and the like.
+// It returns the content's words joined by single spaces.
+func extractMainText(body []byte, pageURL *url.URL) (string, error) {
+	article, err := readability.FromReader(bytes.NewReader(body), pageURL)
+	if err != nil || article.Node == nil {
+		return "", err
+	}
+	var words []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode { //word by word, so text either side of a tag doesn't run together
+			words = append(words, strings.Fields(n.Data)...)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(article.Node)
+	return strings.Join(words, " "), nil
+}
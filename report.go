@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runReport is the report command, writing a saved crawl in another format or as an audit without crawling again
+func runReport(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: monzo report [flags] crawl.json")
+		flags.PrintDefaults()
+	}
+	var out outputFlags
+	var certExpiryDays int
+	var inspectAfter bool
+	out.register(flags)
+	flags.BoolVar(&inspectAfter, "inspect", false, "Once the crawl is output, read commands on stdin to query it and export parts of it")
+	flags.IntVar(&certExpiryDays, "cert-expiry-days", int(DefaultCertExpiryWarning/(24*time.Hour)), "How soon a certificate can expire before -audit tls flags it, in days")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("report needs a crawl written in the json or ndjson format")
+	}
+	roots, _, err := loadCrawl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defaultFormat := "text" //to stdout, rather than logged as a crawl would be
+	if out.uploadURL != "" || out.perSeedDir != "" || out.outFile != "" || len(out.outs) > 0 {
+		defaultFormat = ""
+	}
+	output, err := out.output(defaultFormat, time.Duration(certExpiryDays)*24*time.Hour, nil)
+	if err != nil {
+		return err
+	}
+	output(roots)
+	if inspectAfter {
+		inspect(os.Stdin, os.Stdout, roots, nil)
+	}
+	out.wait()
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	retryBackoff  = time.Second //wait before the first retry, doubling for each after
+	maxRetryAfter = time.Minute //longest a Retry-After header can make a retry wait
+)
+
+// do sends req, trying again up to Retries times after network errors, 429s, 5xxs and bodies too slow for BodyTimeout
+// or MinRate. It backs off exponentially between tries, or as long as the server's Retry-After says.
+func (c *Crawler) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		resp, err := c.Client.Do(req)
+		latency := time.Since(start)
+		if err == nil && c.watchesBody(resp) {
+			resp, err = c.readBody(resp)
+		}
+		if c.Adaptive {
+			c.observe(req.URL, latency, retryable(resp, err))
+		}
+		if attempt >= c.Retries || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		wait := backoff
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if after := retryAfter(resp); after > 0 {
+				wait = min(after, maxRetryAfter)
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) //so the connection can be reused
+			resp.Body.Close()
+		}
+		log.Warningf("retrying %s in %s after %s", req.URL.String(), wait, reason)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// observe feeds how a response went to its origin's throttle
+func (c *Crawler) observe(u *url.URL, latency time.Duration, struggling bool) {
+	if window, shrank := c.throttle(u).observe(latency, struggling); shrank {
+		log.Debugf("throttling %s://%s to %d at once", u.Scheme, u.Host, window)
+	}
+}
+
+// retryable reports whether a request that went like this might go better if tried again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !isTLSVerificationError(err) //a bad certificate will still be bad
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryAfter is how long the response's Retry-After asks to wait for, in seconds or until a date, 0 if it doesn't say
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// revisitIntervals are the names -revisit takes for the usual intervals, besides Go durations like 90m
+var revisitIntervals = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"never":   1<<63 - 1,
+}
+
+// RevisitRule is how often the URLs whose path and query match Pattern need fetching again
+type RevisitRule struct {
+	Pattern *regexp.Regexp
+	Every   time.Duration
+}
+
+// parseRevisitRules parses rules given as pattern=interval, like /news/=hourly or ^/docs/=168h, the pattern being a
+// regexp and the interval split off at the last =
+func parseRevisitRules(specs []string) ([]RevisitRule, error) {
+	var rules []RevisitRule
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("revisit rule %s isn't pattern=interval", spec)
+		}
+		pattern, err := regexp.Compile(spec[:i])
+		if err != nil {
+			return nil, fmt.Errorf("revisit rule %s: %w", spec, err)
+		}
+		every, ok := revisitIntervals[spec[i+1:]]
+		if !ok {
+			if every, err = time.ParseDuration(spec[i+1:]); err != nil {
+				return nil, fmt.Errorf("revisit rule %s has an interval that is neither a duration nor hourly, daily, weekly, monthly or never", spec)
+			}
+		}
+		rules = append(rules, RevisitRule{Pattern: pattern, Every: every})
+	}
+	return rules, nil
+}
+
+// fresh returns the Prior fetch of u if the first Revisit rule it matches says it doesn't need fetching again yet
+func (c *Crawler) fresh(u *url.URL) *Page {
+	prior, ok := c.Prior[u.String()]
+	if !ok || !prior.crawled() || prior.Fetched.IsZero() {
+		return nil
+	}
+	target := u.RequestURI()
+	for _, rule := range c.Revisit {
+		if rule.Pattern.MatchString(target) {
+			if time.Since(prior.Fetched) < rule.Every {
+				return prior
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// reuse fills in target from its prior fetch instead of fetching it, following the links that fetch found
+func (c *Crawler) reuse(ctx context.Context, target, prior *Page, depth int) {
+	log.Debugf("reusing %s, fetched %s ago", prior.URL.String(), time.Since(prior.Fetched).Round(time.Second))
+	*target = *prior
+	target.Links, target.Structure, target.Partial = nil, nil, false //about the earlier crawl as a whole
+	target.ExternalLinks = slices.Clone(prior.ExternalLinks)         //the prior may be shared with other crawls
+	if c.Previous != nil {
+		unchanged := false
+		target.Changed = &unchanged
+	}
+	for _, link := range prior.Links {
+		c.parseLink(ctx, link.URL.String(), target, depth)
+	}
+	c.pageDone(target)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsLimit is the most of a robots.txt read, as Google does
+const robotsLimit = 500 << 10
+
+// robotsInfo is what an origin's robots.txt asks of the crawl, fetched once by whichever fetch from the origin comes first
+type robotsInfo struct {
+	once  sync.Once
+	group robotsGroup
+}
+
+// robotsGroup is what the group of a robots.txt for one user agent asks of it
+type robotsGroup struct {
+	crawlDelay time.Duration
+	rules      []robotsRule
+}
+
+// robotsRule is an Allow or Disallow line, its path pattern as a regexp
+type robotsRule struct {
+	allow   bool
+	pattern string
+	match   *regexp.Regexp
+}
+
+// crawlDelay is the Crawl-delay u's origin asks of the user agent fetching from it, 0 if it doesn't ask or IgnoreCrawlDelay is set
+func (c *Crawler) crawlDelay(ctx context.Context, u *url.URL) time.Duration {
+	if c.IgnoreCrawlDelay {
+		return 0
+	}
+	return c.robotsGroup(ctx, u).crawlDelay
+}
+
+// robotsGroup is what u's origin's robots.txt asks of the user agent fetching from it, fetching it the first time
+func (c *Crawler) robotsGroup(ctx context.Context, u *url.URL) robotsGroup {
+	origin := u.Scheme + "://" + u.Host
+	c.mutex.Lock()
+	info, ok := c.robots[origin]
+	if !ok {
+		info = &robotsInfo{}
+		c.robots[origin] = info
+	}
+	c.mutex.Unlock()
+	info.once.Do(func() {
+		info.group = c.fetchRobots(ctx, origin)
+		if info.group.crawlDelay > 0 && !c.IgnoreCrawlDelay {
+			log.Infof("%s asks for a Crawl-delay of %s", origin, info.group.crawlDelay)
+		}
+	})
+	return info.group
+}
+
+func (c *Crawler) fetchRobots(ctx context.Context, origin string) robotsGroup {
+	req, err := c.newRequest(context.WithoutCancel(ctx), "GET", origin+"/robots.txt") //it holds for the rest of the crawl, whichever fetch asked
+	if err != nil {
+		return robotsGroup{}
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		log.Debugf("couldn't fetch %s: %v", req.URL.String(), err)
+		return robotsGroup{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return robotsGroup{}
+	}
+	agent := req.Header.Get("User-Agent")
+	if agent == "" {
+		agent = "Go-http-client"
+	}
+	return parseRobots(io.LimitReader(resp.Body, robotsLimit), agent)
+}
+
+// parseRobots finds the Crawl-delay and Allow and Disallow rules in robots.txt for the group naming the longest part of
+// agent, or the * group if none does
+func parseRobots(r io.Reader, agent string) robotsGroup {
+	agent = strings.ToLower(agent)
+	var group, fallback robotsGroup
+	matched := -1
+	var names []string //user agents of the group being read
+	inRules := false   //past the group's User-agent lines, so another starts a new group
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if key == "user-agent" {
+			if inRules {
+				names, inRules = nil, false
+			}
+			names = append(names, strings.ToLower(value))
+			continue
+		}
+		inRules = true
+		wildcard, longest := false, -1
+		for _, name := range names {
+			switch {
+			case name == "*":
+				wildcard = true
+			case strings.Contains(agent, name) && len(name) > longest:
+				longest = len(name)
+			}
+		}
+		if longest > matched {
+			group, matched = robotsGroup{}, longest //a group naming more of agent than any before it
+		}
+		var targets []*robotsGroup
+		if longest >= 0 && longest == matched {
+			targets = append(targets, &group)
+		}
+		if wildcard {
+			targets = append(targets, &fallback)
+		}
+		for _, target := range targets {
+			target.add(key, value)
+		}
+	}
+	if matched >= 0 {
+		return group
+	}
+	return fallback
+}
+
+// add records a line of robots.txt, ignoring those it doesn't understand
+func (g *robotsGroup) add(key, value string) {
+	switch key {
+	case "crawl-delay":
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+			g.crawlDelay = time.Duration(seconds * float64(time.Second))
+		}
+	case "allow", "disallow":
+		if value == "" { //an empty Disallow allows everything, as if it wasn't there
+			return
+		}
+		pattern := regexp.QuoteMeta(value)
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		if strings.HasSuffix(pattern, `\$`) {
+			pattern = strings.TrimSuffix(pattern, `\$`) + "$"
+		}
+		g.rules = append(g.rules, robotsRule{allow: key == "allow", pattern: value, match: regexp.MustCompile("^" + pattern)})
+	}
+}
+
+// allowed reports whether the rules let u be fetched, and the rule deciding it if one did. As Google has it, the rule
+// with the longest pattern matching u's path wins, and Allow wins a tie.
+func (g robotsGroup) allowed(u *url.URL) (bool, *robotsRule) {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	var decided *robotsRule
+	for i, rule := range g.rules {
+		if !rule.match.MatchString(path) {
+			continue
+		}
+		if decided == nil || len(rule.pattern) > len(decided.pattern) || len(rule.pattern) == len(decided.pattern) && rule.allow {
+			decided = &g.rules[i]
+		}
+	}
+	return decided == nil || decided.allow, decided
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"net/url"
+)
+
+// sampled reports whether u falls in the crawl's Sample. It goes by a hash of the URL rather than chance, so crawling
+// the same site again samples the same pages and the two can be compared.
+func (c *Crawler) sampled(u *url.URL) bool {
+	if c.Sample <= 0 || c.Sample >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(u.String()))
+	return float64(h.Sum64())/math.MaxUint64 < c.Sample
+}
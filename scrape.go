@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ScrapeRule extracts one field from every page, from the elements a CSS selector or XPath expression picks out
+type ScrapeRule struct {
+	Name  string
+	CSS   string `json:"css"`
+	XPath string `json:"xpath"`
+	Attr  string `json:"attr"` //take this attribute of each element rather than its text
+	All   bool   `json:"all"`  //keep every match as a list rather than just the first
+
+	css   cascadia.Selector
+	xpath *xpath.Expr
+}
+
+// loadScrapeRules reads a JSON object mapping field names to rules, like {"price": {"css": ".price"}, "images": {"xpath": "//img", "attr": "src", "all": true}}
+func loadScrapeRules(path string) ([]*ScrapeRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byName map[string]*ScrapeRule
+	if err := json.Unmarshal(data, &byName); err != nil {
+		return nil, fmt.Errorf("couldn't parse scrape rules %s: %v", path, err)
+	}
+	var rules []*ScrapeRule
+	for name, rule := range byName {
+		rule.Name = name
+		switch {
+		case (rule.CSS == "") == (rule.XPath == ""):
+			return nil, fmt.Errorf("scrape rule %q needs exactly one of css or xpath", name)
+		case rule.CSS != "":
+			rule.css, err = cascadia.Compile(rule.CSS)
+		default:
+			rule.xpath, err = xpath.Compile(rule.XPath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad scrape rule %q: %v", name, err)
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("no scrape rules in " + path)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+// scrape evaluates every rule on a page, giving each field that matched a string, or a list of them for All rules
+func scrape(doc *html.Node, rules []*ScrapeRule) map[string]any {
+	fields := make(map[string]any)
+	for _, rule := range rules {
+		var nodes []*html.Node
+		if rule.css != nil {
+			nodes = rule.css.MatchAll(doc)
+		} else {
+			nodes = htmlquery.QuerySelectorAll(doc, rule.xpath)
+		}
+		var values []string
+		for _, n := range nodes {
+			if rule.Attr != "" {
+				if !nodeHasAttr(n, rule.Attr) {
+					continue
+				}
+				values = append(values, strings.TrimSpace(nodeAttr(n, rule.Attr)))
+			} else {
+				values = append(values, strings.Join(strings.Fields(nodeText(n)), " "))
+			}
+			if !rule.All {
+				break
+			}
+		}
+		switch {
+		case len(values) == 0:
+		case rule.All:
+			fields[rule.Name] = values
+		default:
+			fields[rule.Name] = values[0]
+		}
+	}
+	return fields
+}
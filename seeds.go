@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readLines reads a list one item per line from a file, or stdin if path is "-", skipping blank lines and # comments
+func readLines(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseSeeds turns seed strings into absolute URLs, failing on the first that isn't one. A bare host like example.com
+// is taken to be under https.
+func parseSeeds(seedStrings []string) ([]*url.URL, error) {
+	var seeds []*url.URL
+	for _, seedString := range seedStrings {
+		rawURL := seedString
+		if !strings.Contains(rawURL, "://") {
+			rawURL = "https://" + rawURL
+		}
+		seed, err := url.Parse(rawURL)
+		if err != nil || seed.Host == "" {
+			return nil, fmt.Errorf("couldn't parse seed URL %q", seedString)
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// seedFilename names the file a seed's webmap is written to in a per-seed output directory
+func seedFilename(dir string, seed *url.URL, format string) string {
+	name := strings.Trim(seed.Host+seed.Path, "/")
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == ':' || r == '\\' || r == '?' || r == '*' {
+			return '_'
+		}
+		return r
+	}, name)
+	return filepath.Join(dir, name+"."+format)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sink receives each page as soon as the crawl has finished with it, for streaming results elsewhere
+type Sink interface {
+	Write(ctx context.Context, page *Page) error
+	Close() error
+}
+
+// sinks maps a -sink URL scheme to the function that opens that kind of sink
+var sinks = map[string]func(u *url.URL) (Sink, error){
+	"kafka":               newKafkaSink,
+	"nats":                newNATSSink,
+	"elasticsearch":       newElasticsearchSink,
+	"elasticsearch+https": newElasticsearchSink,
+	"mongodb":             newMongoSink,
+	"mongodb+srv":         newMongoSink,
+}
+
+func sinkSchemes() []string {
+	schemes := make([]string, 0, len(sinks))
+	for scheme := range sinks {
+		schemes = append(schemes, scheme+"://")
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// openSink opens the sink described by a URL like kafka://broker:9092/topic
+func openSink(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	open, ok := sinks[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown sink %q, expected one of %s", rawURL, strings.Join(sinkSchemes(), ", "))
+	}
+	return open(u)
+}
+
+// pageRecord is the flat form of a page that sinks publish, one per crawled page
+type pageRecord struct {
+	URL                 string            `json:"url"`
+	Status              int               `json:"status"`
+	ContentType         string            `json:"content_type,omitempty"`
+	LatencyMS           float64           `json:"latency_ms,omitempty"`
+	Depth               int               `json:"depth,omitempty"`
+	Referrer            string            `json:"referrer,omitempty"`
+	Fallback            string            `json:"fallback,omitempty"`
+	Redirects           []Redirect        `json:"redirects,omitempty"`
+	SecurityHeaders     map[string]string `json:"security_headers,omitempty"`
+	Size                int64             `json:"size,omitempty"`
+	ContentHash         string            `json:"content_hash,omitempty"`
+	Truncated           bool              `json:"truncated,omitempty"`
+	BodyKey             string            `json:"body_key,omitempty"`
+	Fingerprint         string            `json:"fingerprint,omitempty"`
+	Changed             *bool             `json:"changed,omitempty"`
+	Partial             bool              `json:"partial,omitempty"`
+	PageRank            float64           `json:"pagerank,omitempty"`
+	Hub                 float64           `json:"hub,omitempty"`
+	Authority           float64           `json:"authority,omitempty"`
+	ClickDepth          int               `json:"click_depth,omitempty"`
+	Structure           *SiteStats        `json:"structure,omitempty"`
+	Canonical           string            `json:"canonical,omitempty"`
+	Sitemap             []string          `json:"sitemap,omitempty"`
+	Error               string            `json:"error,omitempty"`
+	TLSError            string            `json:"tls_error,omitempty"`
+	TLS                 *TLSInfo          `json:"tls,omitempty"`
+	UserAgent           string            `json:"user_agent,omitempty"`
+	Title               string            `json:"title,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	Keywords            []string          `json:"keywords,omitempty"`
+	Robots              []string          `json:"robots,omitempty"`
+	H1s                 []string          `json:"h1s,omitempty"`
+	Lang                string            `json:"lang,omitempty"`
+	DetectedLang        string            `json:"detected_lang,omitempty"`
+	StructuredData      []any             `json:"structured_data,omitempty"`
+	Fields              map[string]any    `json:"fields,omitempty"`
+	Text                string            `json:"text,omitempty"`
+	MainText            string            `json:"main_text,omitempty"`
+	WordCount           int               `json:"word_count,omitempty"`
+	Relevance           float64           `json:"relevance,omitempty"`
+	EmptyLinks          int               `json:"empty_links,omitempty"`
+	Aliases             []string          `json:"aliases,omitempty"`
+	Matches             []GrepMatch       `json:"matches,omitempty"`
+	Emails              []string          `json:"emails,omitempty"`
+	Phones              []string          `json:"phones,omitempty"`
+	Links               []string          `json:"links"`
+	Statics             []string          `json:"statics"`
+	MixedContent        []string          `json:"mixed_content,omitempty"`
+	AccessibilityIssues []string          `json:"accessibility_issues,omitempty"`
+	Assets              []*Asset          `json:"assets,omitempty"`
+	ExternalLinks       []string          `json:"external_links,omitempty"`
+	External            []*Asset          `json:"external,omitempty"`
+	Anchors             []*Anchor         `json:"anchors,omitempty"`
+	Alternates          []*Alternate      `json:"alternates,omitempty"`
+	Fetched             *time.Time        `json:"fetched,omitempty"` //unset for pages that were never fetched
+}
+
+func newPageRecord(page *Page) pageRecord {
+	record := pageRecord{
+		URL:                 page.URL.String(),
+		Status:              page.Status,
+		ContentType:         page.ContentType,
+		LatencyMS:           milliseconds(page.Latency),
+		Depth:               page.Depth,
+		Referrer:            page.Referrer,
+		Fallback:            page.Fallback,
+		Redirects:           page.Redirects,
+		SecurityHeaders:     page.SecurityHeaders,
+		Size:                page.Size,
+		ContentHash:         page.ContentHash,
+		Truncated:           page.Truncated,
+		BodyKey:             page.BodyKey,
+		Fingerprint:         page.Fingerprint,
+		Changed:             page.Changed,
+		Partial:             page.Partial,
+		PageRank:            page.PageRank,
+		Hub:                 page.Hub,
+		Authority:           page.Authority,
+		ClickDepth:          page.ClickDepth,
+		Structure:           page.Structure,
+		Canonical:           page.Canonical,
+		Sitemap:             page.Sitemap,
+		Error:               page.Error,
+		TLSError:            page.TLSError,
+		TLS:                 page.TLS,
+		UserAgent:           page.UserAgent,
+		Title:               page.Title,
+		Description:         page.Description,
+		Keywords:            page.Keywords,
+		Robots:              page.Robots,
+		H1s:                 page.H1s,
+		Lang:                page.Lang,
+		DetectedLang:        page.DetectedLang,
+		StructuredData:      page.StructuredData,
+		Fields:              page.Fields,
+		Anchors:             page.Anchors,
+		Alternates:          page.Alternates,
+		Text:                page.Text,
+		MainText:            page.MainText,
+		WordCount:           page.WordCount,
+		Relevance:           page.Relevance,
+		EmptyLinks:          page.EmptyLinks,
+		Aliases:             page.Aliases,
+		Matches:             page.Matches,
+		Emails:              page.Emails,
+		Phones:              page.Phones,
+		Links:               []string{},
+		Statics:             []string{},
+		MixedContent:        page.MixedContent,
+		AccessibilityIssues: page.AccessibilityIssues,
+		Assets:              page.Assets,
+		ExternalLinks:       urlStrings(page.ExternalLinks),
+		External:            page.External,
+	}
+	if !page.Fetched.IsZero() {
+		record.Fetched = &page.Fetched
+	}
+	for _, link := range page.Links {
+		record.Links = append(record.Links, link.URL.String())
+	}
+	for _, static := range page.Statics {
+		record.Statics = append(record.Statics, static.String())
+	}
+	return record
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"net/url"
+	"strings"
+)
+
+// kafkaSink publishes one JSON message per page, keyed by URL so a page's messages stay on one partition
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+// newKafkaSink opens a sink for kafka://broker1:9092,broker2:9092/topic
+func newKafkaSink(u *url.URL) (Sink, error) {
+	topic := strings.Trim(u.Path, "/")
+	if u.Host == "" || topic == "" {
+		return nil, errors.New("kafka sink needs brokers and a topic, like kafka://broker:9092/topic")
+	}
+	writer := &kafka.Writer{
+		Addr:     kafka.TCP(strings.Split(u.Host, ",")...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+		Async:    true, //batch in the background rather than holding up crawl workers, errors come back through Completion
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				log.Errorf("failed to publish %d pages to kafka: %v", len(messages), err)
+			}
+		},
+	}
+	return &kafkaSink{writer: writer}, nil
+}
+
+func (s *kafkaSink) Write(ctx context.Context, page *Page) error {
+	value, err := json.Marshal(newPageRecord(page))
+	if err != nil {
+		return err
+	}
+	return s.writer.WriteMessages(ctx, kafka.Message{Key: []byte(page.URL.String()), Value: value})
+}
+
+// Close flushes any batched messages
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/nats-io/nats.go"
+	"net/url"
+	"strings"
+)
+
+// natsSink publishes one JSON message per page to a subject
+type natsSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// natsAddress splits nats://host:4222/subject into a server URL and a subject
+func natsAddress(u *url.URL) (string, string, error) {
+	subject := strings.Trim(u.Path, "/")
+	if u.Host == "" || subject == "" {
+		return "", "", errors.New("nats needs a server and a subject, like nats://localhost:4222/subject")
+	}
+	server := url.URL{Scheme: "nats", Host: u.Host, User: u.User}
+	return server.String(), subject, nil
+}
+
+// newNATSSink opens a sink for nats://host:4222/subject
+func newNATSSink(u *url.URL) (Sink, error) {
+	server, subject, err := natsAddress(u)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := nats.Connect(server, nats.Name("monzo sink"))
+	if err != nil {
+		return nil, err
+	}
+	return &natsSink{conn: conn, subject: subject}, nil
+}
+
+func (s *natsSink) Write(ctx context.Context, page *Page) error {
+	data, err := json.Marshal(newPageRecord(page))
+	if err != nil {
+		return err
+	}
+	return s.conn.Publish(s.subject, data)
+}
+
+// Close flushes anything still buffered before disconnecting
+func (s *natsSink) Close() error {
+	err := s.conn.Flush()
+	s.conn.Close()
+	return err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// maxSitemaps bounds how many sitemaps a sitemap index can make a crawl fetch
+const maxSitemaps = 100
+
+// sitemapDoc is either a sitemap's urlset or a sitemap index, which lists more sitemaps
+type sitemapDoc struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// fetchSitemap returns every URL in the sitemap at rawURL, following sitemap indexes
+func (c *Crawler) fetchSitemap(ctx context.Context, rawURL string) ([]string, error) {
+	var urls []string
+	queue, fetched := []string{rawURL}, 0
+	for len(queue) > 0 && fetched < maxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		fetched++
+		doc, err := c.fetchSitemapDoc(ctx, sitemapURL)
+		if err != nil {
+			if sitemapURL == rawURL {
+				return nil, err
+			}
+			log.Warningf("skipping sitemap %s: %v", sitemapURL, err)
+			continue
+		}
+		for _, u := range doc.URLs {
+			urls = append(urls, strings.TrimSpace(u.Loc))
+		}
+		for _, sitemap := range doc.Sitemaps {
+			queue = append(queue, strings.TrimSpace(sitemap.Loc))
+		}
+	}
+	return urls, nil
+}
+
+func (c *Crawler) fetchSitemapDoc(ctx context.Context, rawURL string) (*sitemapDoc, error) {
+	req, err := c.newRequest(ctx, "GET", rawURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var body io.Reader = resp.Body
+	if strings.HasSuffix(req.URL.Path, ".gz") && resp.Header.Get("Content-Encoding") == "" { //a gzipped file, rather than gzipped in transit
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	var doc sitemapDoc
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// defaultSitemap is where a seed's site keeps its sitemap if it hasn't said otherwise
+func defaultSitemap(seed *url.URL) string {
+	return (&url.URL{Scheme: seed.Scheme, Host: seed.Host, Path: "/sitemap.xml"}).String()
+}
+
+// writeSitemap writes the sitemap format, a sitemap.xml of the pages that fetched fine, leaving out those asking not to be
+// indexed and those naming another page as their canonical
+func writeSitemap(w io.Writer, root *Page) error {
+	return writeSitemaps(w, []*Page{root})
+}
+
+// writeSitemaps is writeSitemap for the pages under every root, in the one <urlset>
+func writeSitemaps(w io.Writer, roots []*Page) error {
+	type entry struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod,omitempty"`
+	}
+	urlset := struct {
+		XMLName xml.Name `xml:"urlset"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		URLs    []entry  `xml:"url"`
+	}{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, page := range graphPages(roots) {
+		if page.Status != 200 || slices.Contains(page.Robots, "noindex") || slices.Contains(page.Robots, "none") {
+			continue
+		}
+		if page.Canonical != "" && page.Canonical != page.URL.String() {
+			continue
+		}
+		e := entry{Loc: page.URL.String()}
+		if !page.Fetched.IsZero() {
+			e.LastMod = page.Fetched.Format("2006-01-02")
+		}
+		urlset.URLs = append(urlset.URLs, e)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(urlset); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
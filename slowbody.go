@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minRateGrace is how long a body may take to get going before MinRate applies, as slow starts are common
+const minRateGrace = 5 * time.Second
+
+var errSlowBody = errors.New("response body too slow")
+
+// watchesBody reports whether resp's body needs reading under BodyTimeout and MinRate before it's handed back. Only
+// HTML is, since other bodies are mostly left unread.
+func (c *Crawler) watchesBody(resp *http.Response) bool {
+	return (c.BodyTimeout > 0 || c.MinRate > 0) && !retryable(resp, nil) && isHTMLType(resp.Header.Get("Content-Type"))
+}
+
+// readBody reads all of resp's body in place of it, so a server stalling or trickling it out can be given up on and
+// tried again: it is closed once reading takes longer than BodyTimeout, or goes slower than MinRate after minRateGrace
+func (c *Crawler) readBody(resp *http.Response) (*http.Response, error) {
+	defer resp.Body.Close()
+	start := time.Now()
+	body := &countingReader{r: resp.Body}
+	var once sync.Once
+	var slow error
+	abandon := func(reason error) {
+		once.Do(func() {
+			slow = reason
+			resp.Body.Close() //unblocks the read
+		})
+	}
+	if c.BodyTimeout > 0 {
+		timer := time.AfterFunc(c.BodyTimeout, func() {
+			abandon(fmt.Errorf("%w: still reading after %s", errSlowBody, c.BodyTimeout))
+		})
+		defer timer.Stop()
+	}
+	if c.MinRate > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case now := <-ticker.C:
+					elapsed := now.Sub(start)
+					if elapsed >= minRateGrace && float64(body.n.Load()) < float64(c.MinRate)*elapsed.Seconds() {
+						abandon(fmt.Errorf("%w: under %d bytes a second", errSlowBody, c.MinRate))
+						return
+					}
+				}
+			}
+		}()
+	}
+	var limited io.Reader = body
+	if c.MaxHTMLSize > 0 {
+		limited = io.LimitReader(body, c.MaxHTMLSize+1) //the one more byte tells whether there was more
+	}
+	data, err := io.ReadAll(limited)
+	once.Do(func() {}) //so it can't be abandoned from here on, and slow is safe to read
+	if slow != nil {
+		return nil, slow
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// countingReader counts the bytes read through it, for checking the rate they arrive at while they are read
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StoredStatic is where one downloaded static ended up in a StaticStore
+type StoredStatic struct {
+	Blob        string `json:"blob,omitempty"` //sha256 of the body, the blob's name under blobs/
+	Size        int64  `json:"size,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	once sync.Once
+}
+
+// StaticStore downloads every static pages refer to once, keeping each distinct body once however many URLs serve it.
+// Blobs are saved as dir/blobs/<sha256>, and Finish writes dir/index.json mapping each URL to its blob.
+type StaticStore struct {
+	Dir string
+
+	mutex   sync.Mutex
+	statics map[string]*StoredStatic
+}
+
+func NewStaticStore(dir string) *StaticStore {
+	return &StaticStore{Dir: dir, statics: make(map[string]*StoredStatic)}
+}
+
+// download fetches each of a page's statics not already in the store
+func (s *StaticStore) download(ctx context.Context, c *Crawler, target *Page) {
+	for _, static := range (*target).Statics {
+		s.mutex.Lock()
+		stored, ok := s.statics[static.String()]
+		if !ok {
+			stored = &StoredStatic{}
+			s.statics[static.String()] = stored
+		}
+		s.mutex.Unlock()
+		stored.once.Do(func() {
+			if err := s.fetch(ctx, c, static.String(), stored); err != nil {
+				stored.Error = err.Error()
+				log.Warningf("failed to download static %s: %v", static.String(), err)
+			}
+		})
+	}
+}
+
+func (s *StaticStore) fetch(ctx context.Context, c *Crawler, rawURL string, stored *StoredStatic) error {
+	req, err := c.newRequest(ctx, "GET", rawURL)
+	if err != nil {
+		return err
+	}
+	release, ok := c.acquireHost(ctx, req.URL)
+	if !ok {
+		return ctx.Err()
+	}
+	defer release()
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	stored.Status, stored.ContentType = resp.StatusCode, resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	blob, size, err := s.store(resp.Body)
+	if err != nil {
+		return err
+	}
+	stored.Blob, stored.Size = blob, size
+	return nil
+}
+
+// store writes a body to a temporary file while hashing it, then moves it to its blob unless that body is already stored
+func (s *StaticStore) store(body io.Reader) (string, int64, error) {
+	blobs := filepath.Join(s.Dir, "blobs")
+	if err := os.MkdirAll(blobs, 0755); err != nil {
+		return "", 0, err
+	}
+	f, err := os.CreateTemp(blobs, ".download-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(f.Name()) //a no-op once renamed
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	blob := hex.EncodeToString(hash.Sum(nil))
+	path := filepath.Join(blobs, blob)
+	if _, err := os.Stat(path); err == nil {
+		return blob, size, nil
+	}
+	return blob, size, os.Rename(f.Name(), path)
+}
+
+// Finish writes the store's index, returning how many URLs and distinct blobs it holds
+func (s *StaticStore) Finish() (int, int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	blobs := make(map[string]struct{})
+	for _, stored := range s.statics {
+		if stored.Blob != "" {
+			blobs[stored.Blob] = struct{}{}
+		}
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return 0, 0, err
+	}
+	index, err := json.MarshalIndent(s.statics, "", "  ")
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(s.statics), len(blobs), os.WriteFile(filepath.Join(s.Dir, "index.json"), index, 0644)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+// hasStructuredData reports whether a token starts a JSON-LD block or a microdata item, so only those pages get parsed for it
+func hasStructuredData(token html.Token) bool {
+	if token.DataAtom == atom.Script {
+		return isJSONLD(attrValue(token, "type"))
+	}
+	return hasAttr(token, "itemscope")
+}
+
+func isJSONLD(scriptType string) bool {
+	return strings.EqualFold(strings.TrimSpace(scriptType), "application/ld+json")
+}
+
+// parseStructuredData returns the page's JSON-LD blocks and top level microdata items, each decoded like JSON.
+// Microdata items become objects with @type and @id from itemtype and itemid, alongside their properties.
+func parseStructuredData(doc *html.Node, base *url.URL) []any {
+	var data []any
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Script && isJSONLD(nodeAttr(n, "type")) {
+				var block any
+				if err := json.Unmarshal([]byte(nodeText(n)), &block); err != nil {
+					log.Warningf("invalid JSON-LD on page %s: %v", base.String(), err)
+				} else {
+					data = append(data, block)
+				}
+				return
+			}
+			if nodeHasAttr(n, "itemscope") && !nodeHasAttr(n, "itemprop") {
+				data = append(data, microdataItem(n, base))
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return data
+}
+
+// microdataItem collects the properties of the item whose itemscope is on n
+func microdataItem(n *html.Node, base *url.URL) map[string]any {
+	item := make(map[string]any)
+	if itemType := nodeAttr(n, "itemtype"); itemType != "" {
+		item["@type"] = itemType
+	}
+	if itemID := nodeAttr(n, "itemid"); itemID != "" {
+		item["@id"] = resolve(base, itemID)
+	}
+	props := make(map[string][]any)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if names := strings.Fields(nodeAttr(child, "itemprop")); len(names) > 0 {
+				var value any
+				if nodeHasAttr(child, "itemscope") {
+					value = microdataItem(child, base)
+				} else {
+					value = microdataValue(child, base)
+				}
+				for _, name := range names {
+					props[name] = append(props[name], value)
+				}
+			}
+			if !nodeHasAttr(child, "itemscope") { //a nested item's properties are its own
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	for name, values := range props {
+		if len(values) == 1 {
+			item[name] = values[0]
+		} else {
+			item[name] = values
+		}
+	}
+	return item
+}
+
+// microdataValue is a property's value, which depends on the element it is on as in the microdata spec
+func microdataValue(n *html.Node, base *url.URL) string {
+	switch n.DataAtom {
+	case atom.Meta:
+		return nodeAttr(n, "content")
+	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
+		return resolve(base, nodeAttr(n, "src"))
+	case atom.A, atom.Area, atom.Link:
+		return resolve(base, nodeAttr(n, "href"))
+	case atom.Object:
+		return resolve(base, nodeAttr(n, "data"))
+	case atom.Data, atom.Meter:
+		return nodeAttr(n, "value")
+	case atom.Time:
+		if nodeHasAttr(n, "datetime") {
+			return nodeAttr(n, "datetime")
+		}
+	}
+	if nodeHasAttr(n, "content") {
+		return nodeAttr(n, "content")
+	}
+	return strings.Join(strings.Fields(nodeText(n)), " ")
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func nodeAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func nodeHasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeText(n *html.Node) string {
+	var text strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return text.String()
+}
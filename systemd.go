@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sdNotify tells systemd how the service is doing, with states like READY=1, over $NOTIFY_SOCKET. It does nothing
+// unless run by systemd as Type=notify.
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	if strings.HasPrefix(socket, "@") { //in the abstract namespace
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		log.Warning("couldn't notify systemd:", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Warning("couldn't notify systemd:", err)
+	}
+}
+
+// watchdogInterval is how often systemd wants to hear the service is alive under WatchdogSec, 0 if it doesn't
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) { //meant for a process that started this one
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// watchdog pings systemd's watchdog at half its interval forever, so long as jobs' lock can be taken: were it
+// deadlocked, the pings would stop and systemd would restart the service
+func watchdog(jobs *JobManager) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		jobs.Counts()
+		sdNotify("WATCHDOG=1")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// slowResponse is how many times a host's average response time a response can take before it counts as the host struggling
+const slowResponse = 3
+
+// hostThrottle limits how many fetches from one origin are in flight by how well it has been coping, AIMD style: the
+// window halves whenever a response is a 429, a 5xx, a network error or unusually slow, and grows by one for each
+// window's worth of good responses, back up to the most it started at
+type hostThrottle struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	window   float64
+	most     float64
+	inFlight int
+	latency  time.Duration //moving average of response times, 0 before the first
+}
+
+func newHostThrottle(most int) *hostThrottle {
+	t := &hostThrottle{window: float64(most), most: float64(most)}
+	t.cond = sync.NewCond(&t.mutex)
+	return t
+}
+
+// acquire waits for room in the window, returning false if ctx ends first
+func (t *hostThrottle) acquire(ctx context.Context) bool {
+	stop := context.AfterFunc(ctx, func() {
+		t.mutex.Lock()
+		t.cond.Broadcast()
+		t.mutex.Unlock()
+	})
+	defer stop()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for t.inFlight >= int(t.window) && ctx.Err() == nil {
+		t.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	t.inFlight++
+	return true
+}
+
+func (t *hostThrottle) release() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.inFlight--
+	t.cond.Signal()
+}
+
+// observe adjusts the window by how a response went, reporting the new window if it shrank
+func (t *hostThrottle) observe(latency time.Duration, struggling bool) (int, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.latency > 0 && latency > slowResponse*t.latency {
+		struggling = true
+	}
+	if t.latency == 0 {
+		t.latency = latency
+	} else {
+		t.latency += (latency - t.latency) / 5
+	}
+	if struggling {
+		t.window = max(t.window/2, 1)
+		return int(t.window), true
+	}
+	t.window = min(t.window+1/t.window, t.most)
+	t.cond.Broadcast() //the window may have grown enough for another
+	return 0, false
+}
+
+// throttle returns the controller for u's origin, when Adaptive is set
+func (c *Crawler) throttle(u *url.URL) *hostThrottle {
+	origin := u.Scheme + "://" + u.Host
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t, ok := c.limits[origin]
+	if !ok {
+		most := c.Concurrency
+		if c.HostConcurrency > 0 {
+			most = c.HostConcurrency
+		}
+		t = newHostThrottle(most)
+		c.limits[origin] = t
+	}
+	return t
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// loadRootCAs returns the system roots plus any PEM certificates in caFile, or nil for just the system roots
+func loadRootCAs(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// verifyPeer checks a connection's certificates like crypto/tls would have, for connections where verification was skipped.
+// It returns nil if the connection was verified when it was made.
+func verifyPeer(state *tls.ConnectionState, host string, roots *x509.CertPool) error {
+	if state == nil || len(state.VerifiedChains) > 0 {
+		return nil
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificates")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: intermediates})
+	return err
+}
+
+// isTLSVerificationError reports whether a failed request failed because the server's certificate didn't verify
+func isTLSVerificationError(err error) bool {
+	var verification *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &verification) || errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+// DefaultCertExpiryWarning is how close to expiry a certificate gets before it is warned about
+const DefaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// TLSInfo describes the TLS connection to a host and the certificates it presented, recorded once per host
+type TLSInfo struct {
+	Host         string      `json:"host"`
+	Version      string      `json:"version"`
+	CipherSuite  string      `json:"cipher_suite"`
+	Certificates []*CertInfo `json:"certificates"` //the leaf first, then whatever chain the server sent
+}
+
+// CertInfo is the part of a certificate worth auditing
+type CertInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+}
+
+func newTLSInfo(host string, state *tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{Host: host, Version: tls.VersionName(state.Version), CipherSuite: tls.CipherSuiteName(state.CipherSuite)}
+	for _, cert := range state.PeerCertificates {
+		info.Certificates = append(info.Certificates, &CertInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			DNSNames:  cert.DNSNames,
+		})
+	}
+	return info
+}
+
+// warnings lists what is wrong with the connection: protocols older than TLS 1.2,
+// and certificates that have expired, aren't valid yet or expire within the given time
+func (info *TLSInfo) warnings(now time.Time, expiry time.Duration) []string {
+	var warnings []string
+	if version, ok := tlsVersions[info.Version]; !ok || version < tls.VersionTLS12 {
+		warnings = append(warnings, "weak protocol "+info.Version)
+	}
+	for _, cert := range info.Certificates {
+		switch {
+		case now.After(cert.NotAfter):
+			warnings = append(warnings, fmt.Sprintf("certificate %s expired on %s", cert.Subject, cert.NotAfter.Format(time.DateOnly)))
+		case now.Before(cert.NotBefore):
+			warnings = append(warnings, fmt.Sprintf("certificate %s isn't valid until %s", cert.Subject, cert.NotBefore.Format(time.DateOnly)))
+		case cert.NotAfter.Sub(now) < expiry:
+			warnings = append(warnings, fmt.Sprintf("certificate %s expires on %s", cert.Subject, cert.NotAfter.Format(time.DateOnly)))
+		}
+	}
+	return warnings
+}
+
+var tlsVersions = map[string]uint16{
+	tls.VersionName(tls.VersionSSL30): tls.VersionSSL30,
+	tls.VersionName(tls.VersionTLS10): tls.VersionTLS10,
+	tls.VersionName(tls.VersionTLS11): tls.VersionTLS11,
+	tls.VersionName(tls.VersionTLS12): tls.VersionTLS12,
+	tls.VersionName(tls.VersionTLS13): tls.VersionTLS13,
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"golang.org/x/net/publicsuffix"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// proxy rotation modes
+const (
+	RotatePerRequest = "request" //each request uses the next proxy in the list
+	RotatePerHost    = "host"    //each host always goes through the same proxy
+)
+
+// IP families the dialer can be restricted to
+const (
+	IPv4 = "4"
+	IPv6 = "6"
+)
+
+// ClientOptions configures the HTTP client crawls fetch pages with
+type ClientOptions struct {
+	Proxies     []string       //http://, https:// or socks5:// proxy URLs, credentials included as user:pass@
+	ProxyRotate string         //how to pick between several proxies, RotatePerRequest or RotatePerHost
+	CookieFile  string         //cookies to start the jar with, in Netscape or JSON format
+	ClientCert  string         //PEM certificate presented to servers that ask for one, for mutual TLS
+	ClientKey   string         //PEM private key for ClientCert
+	RootCAs     *x509.CertPool //what server certificates must chain to, nil for the system roots
+	Insecure    bool           //skip server certificate verification, for self-signed staging environments
+	IPFamily    string         //IPv4 or IPv6 to only dial that family, "" for either
+	BindAddr    string         //local IP to dial from, for source-IP allowlists
+	Tor         string         //host:port of a Tor SOCKS port to send everything through, instead of Proxies
+	Timeout     time.Duration  //longest a request may take, body and all, 0 for no limit
+	Cache       string         //redis:// URL to cache responses in, "" for no caching
+	CacheTTL    time.Duration  //how long Cache keeps each response
+}
+
+// Tor circuits are slow to build, so connections through one get longer than the defaults to come up
+const (
+	torDialTimeout         = 2 * time.Minute
+	torTLSHandshakeTimeout = time.Minute
+)
+
+// NewClient builds an HTTP client from the options, starting from the default transport's settings
+func NewClient(opts ClientOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: opts.RootCAs, InsecureSkipVerify: opts.Insecure}
+	dialTimeout := 30 * time.Second //as http.DefaultTransport has
+	if opts.Tor != "" {
+		if len(opts.Proxies) > 0 {
+			return nil, errors.New("Tor and other proxies can't both be used")
+		}
+		opts.Proxies = []string{"socks5h://" + opts.Tor} //hostnames go to Tor to resolve, which .onion ones need
+		dialTimeout = torDialTimeout
+		transport.TLSHandshakeTimeout = torTLSHandshakeTimeout
+	}
+	dial, err := newDialer(opts.IPFamily, opts.BindAddr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	transport.DialContext = dial
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, errors.New("a client certificate needs both a certificate and a key")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load client certificate: %v", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	if len(opts.Proxies) > 0 {
+		proxy, err := newProxyRotator(opts.Proxies, opts.ProxyRotate)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxy.proxy
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List}) //keep whatever the site sets, like a browser would
+	if err != nil {
+		return nil, err
+	}
+	if opts.CookieFile != "" {
+		if err := loadCookies(jar, opts.CookieFile); err != nil {
+			return nil, err
+		}
+	}
+	var roundTripper http.RoundTripper = transport
+	if opts.Cache != "" {
+		if opts.CacheTTL <= 0 {
+			return nil, errors.New("cached responses need a TTL")
+		}
+		if roundTripper, err = newRedisCache(transport, opts.Cache, opts.CacheTTL); err != nil {
+			return nil, fmt.Errorf("couldn't connect to the cache: %w", err)
+		}
+	}
+	return &http.Client{Transport: roundTripper, Jar: jar, Timeout: opts.Timeout}, nil
+}
+
+// newDialer returns a DialContext that only uses the given IP family and dials from bindAddr, when they're set
+func newDialer(family, bindAddr string, timeout time.Duration) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if bindAddr != "" {
+		ip := net.ParseIP(bindAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("bind address %q isn't an IP", bindAddr)
+		}
+		switch {
+		case family == IPv4 && ip.To4() == nil, family == IPv6 && ip.To4() != nil:
+			return nil, fmt.Errorf("bind address %s isn't IPv%s", bindAddr, family)
+		case family == "" && ip.To4() != nil:
+			family = IPv4 //a local address of one family can't dial the other
+		case family == "":
+			family = IPv6
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	switch family {
+	case "":
+		return dialer.DialContext, nil
+	case IPv4, IPv6:
+	default:
+		return nil, fmt.Errorf("unknown IP family %q, expected %s or %s", family, IPv4, IPv6)
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" {
+			network += family
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}, nil
+}
+
+// Credentials are sent with requests to URLs within a crawl's scope, and never to anywhere else
+type Credentials struct {
+	User, Password string //HTTP basic auth, if User is set
+	BearerToken    string
+}
+
+// parseCredentials builds Credentials from user:pass and a bearer token, either of which may be empty
+func parseCredentials(basicAuth, bearerToken string) (Credentials, error) {
+	creds := Credentials{BearerToken: bearerToken}
+	if basicAuth != "" {
+		user, password, ok := strings.Cut(basicAuth, ":")
+		if !ok || user == "" {
+			return creds, errors.New("basic auth should look like user:pass")
+		}
+		creds.User, creds.Password = user, password
+	}
+	if creds.User != "" && creds.BearerToken != "" {
+		return creds, errors.New("basic auth and a bearer token can't both be used")
+	}
+	return creds, nil
+}
+
+func (creds Credentials) apply(req *http.Request) {
+	if creds.User != "" {
+		req.SetBasicAuth(creds.User, creds.Password)
+	}
+	if creds.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.BearerToken)
+	}
+}
+
+// rotation picks an index into a list, either the next one every time or the same one for every request to a host
+type rotation struct {
+	perHost bool
+	next    atomic.Uint64
+}
+
+func newRotation(rotate string) (*rotation, error) {
+	switch rotate {
+	case "", RotatePerRequest:
+		return &rotation{}, nil
+	case RotatePerHost:
+		return &rotation{perHost: true}, nil
+	}
+	return nil, fmt.Errorf("unknown rotation %q, expected %s or %s", rotate, RotatePerRequest, RotatePerHost)
+}
+
+func (r *rotation) pick(host string, n int) int {
+	if r.perHost {
+		h := fnv.New32a()
+		h.Write([]byte(host))
+		return int(h.Sum32() % uint32(n))
+	}
+	return int((r.next.Add(1) - 1) % uint64(n))
+}
+
+// proxyRotator hands out proxies from a list, one per request or one per host
+type proxyRotator struct {
+	proxies  []*url.URL
+	rotation *rotation
+}
+
+func newProxyRotator(rawProxies []string, rotate string) (*proxyRotator, error) {
+	rotation, err := newRotation(rotate)
+	if err != nil {
+		return nil, err
+	}
+	r := &proxyRotator{rotation: rotation}
+	for _, rawProxy := range rawProxies {
+		proxy, err := url.Parse(rawProxy)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse proxy %q: %v", rawProxy, err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy %q, expected http://, https:// or socks5://", rawProxy)
+		}
+		r.proxies = append(r.proxies, proxy)
+	}
+	if len(r.proxies) == 0 {
+		return nil, errors.New("no proxies given")
+	}
+	return r, nil
+}
+
+// proxy is an http.Transport Proxy func, which handles auth for every supported scheme itself
+func (r *proxyRotator) proxy(req *http.Request) (*url.URL, error) {
+	return r.proxies[r.rotation.pick(req.URL.Host, len(r.proxies))], nil
+}
+
+// UserAgents is a pool of user agents that crawl requests take turns with, or stick to one of per host
+type UserAgents struct {
+	agents   []string
+	rotation *rotation
+}
+
+func NewUserAgents(agents []string, rotate string) (*UserAgents, error) {
+	if len(agents) == 0 {
+		return nil, errors.New("no user agents given")
+	}
+	rotation, err := newRotation(rotate)
+	if err != nil {
+		return nil, err
+	}
+	return &UserAgents{agents: agents, rotation: rotation}, nil
+}
+
+// pick returns the user agent for a request to host, or "" to leave Go's default in place
+func (u *UserAgents) pick(host string) string {
+	if u == nil {
+		return ""
+	}
+	return u.agents[u.rotation.pick(host, len(u.agents))]
+}
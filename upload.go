@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// uploadPartSize is the multipart chunk size, so results of any size stream up without touching disk
+const uploadPartSize = 16 << 20
+
+// uploadContentTypes are the content types of uploaded results, by format
+var uploadContentTypes = map[string]string{
+	"text":     "text/plain; charset=utf-8",
+	"json":     "application/json",
+	"ndjson":   "application/x-ndjson",
+	"hreflang": "text/plain; charset=utf-8",
+	"grep":     "text/plain; charset=utf-8",
+	"contacts": "text/plain; charset=utf-8",
+	"dot":      "text/vnd.graphviz",
+	"graphml":  "application/graphml+xml",
+	"sitemap":  "application/xml",
+	"csv":      "text/csv",
+	"github":   "text/plain; charset=utf-8",
+	"parquet":  "application/vnd.apache.parquet",
+	"html":     "text/html; charset=utf-8",
+	"warc":     "application/warc",
+}
+
+// upload streams whatever write produces to an object at s3://bucket/key or gs://bucket/key
+func upload(ctx context.Context, dest, format string, write func(io.Writer) error) error {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return err
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return fmt.Errorf("upload destination %q needs a bucket and a key", dest)
+	}
+	client, err := newObjectClient(u)
+	if err != nil {
+		return err
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(write(writer)) //a failed write aborts the upload rather than leaving a truncated object
+	}()
+	_, err = client.PutObject(ctx, bucket, key, reader, -1, minio.PutObjectOptions{
+		ContentType: uploadContentTypes[format],
+		PartSize:    uploadPartSize,
+	})
+	reader.CloseWithError(err) //unblock the writer if the upload gave up early
+	return err
+}
+
+// newObjectClient connects to the store behind an s3:// or gs:// URL.
+// Credentials come from the usual AWS environment variables or files (HMAC keys for GCS), and S3_ENDPOINT points s3:// at any other compatible store.
+func newObjectClient(u *url.URL) (*minio.Client, error) {
+	endpoint, secure := "s3.amazonaws.com", true
+	switch u.Scheme {
+	case "s3":
+		if custom := os.Getenv("S3_ENDPOINT"); custom != "" {
+			parsed, err := url.Parse(custom)
+			if err != nil || parsed.Host == "" {
+				return nil, fmt.Errorf("S3_ENDPOINT %q should look like https://host:port", custom)
+			}
+			endpoint, secure = parsed.Host, parsed.Scheme != "http"
+		}
+	case "gs":
+		endpoint = "storage.googleapis.com"
+	default:
+		return nil, fmt.Errorf("unsupported object store %q, expected s3:// or gs://", u.String())
+	}
+	return minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		}),
+		Secure: secure,
+		Region: os.Getenv("AWS_REGION"),
+	})
+}
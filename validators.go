@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Validators remembers the ETag and Last-Modified of every page fetched, by URL, in a JSON file, so fetching it again
+// in this crawl, a later one or another job can ask for it only if it changed. The page's title and hrefs are kept
+// too, standing in for the body a 304 leaves out.
+type Validators struct {
+	path  string
+	mutex sync.Mutex
+	byURL map[string]*validator
+	dirty bool //changed since it was loaded or last saved
+}
+
+type validator struct {
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Hrefs        []string `json:"hrefs,omitempty"` //what the page linked to, within scope or not
+}
+
+// LoadValidators reads the validators saved at path, starting with none if there's no file there yet
+func LoadValidators(path string) (*Validators, error) {
+	v := &Validators{path: path, byURL: make(map[string]*validator)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &v.byURL); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// condition makes req conditional on the page having changed since it was last fetched, returning what was
+// remembered of it, or nil if it's new
+func (v *Validators) condition(req *http.Request) *validator {
+	v.mutex.Lock()
+	known := v.byURL[req.URL.String()]
+	v.mutex.Unlock()
+	if known == nil {
+		return nil
+	}
+	if known.ETag != "" {
+		req.Header.Set("If-None-Match", known.ETag)
+	}
+	if known.LastModified != "" {
+		req.Header.Set("If-Modified-Since", known.LastModified)
+	}
+	return known
+}
+
+// remember keeps page's validators from header, once it has been fetched in full and its links found
+func (v *Validators) remember(page *Page, header http.Header) {
+	etag, modified := header.Get("ETag"), header.Get("Last-Modified")
+	if page.Status != http.StatusOK || etag == "" && modified == "" {
+		return
+	}
+	known := &validator{ETag: etag, LastModified: modified, Title: page.Title}
+	for _, link := range page.Links {
+		known.Hrefs = append(known.Hrefs, link.URL.String())
+	}
+	for _, link := range page.ExternalLinks {
+		known.Hrefs = append(known.Hrefs, link.String())
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.byURL[page.URL.String()] = known
+	v.dirty = true
+}
+
+// Save writes the validators back to their file if they changed, replacing it whole so a crash can't leave it half
+// written
+func (v *Validators) Save() error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if !v.dirty {
+		return nil
+	}
+	data, err := json.Marshal(v.byURL)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(v.path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+	v.dirty = false
+	return nil
+}
+
+// notModified fills in target, answered with a 304, from what Validators remembered of it, following the links it had
+func (c *Crawler) notModified(ctx context.Context, target *Page, known *validator, depth int) {
+	log.Debugf("%s not modified", (*target).URL.String())
+	(*target).Title = known.Title
+	if c.Previous != nil {
+		(*target).Fingerprint = c.Previous[(*target).URL.String()]
+		unchanged := false
+		(*target).Changed = &unchanged
+	}
+	for _, href := range known.Hrefs {
+		c.parseLink(ctx, href, target, depth)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	_ "embed"
+	"net"
+	"net/http"
+)
+
+//go:embed visualize.html
+var visualizePage []byte
+
+// graphNode is a page as the visualisation draws it, with enough about it for its detail pane
+type graphNode struct {
+	ID          string  `json:"id"`
+	Status      int     `json:"status"`
+	Error       string  `json:"error,omitempty"`
+	Title       string  `json:"title,omitempty"`
+	ContentType string  `json:"content_type,omitempty"`
+	Size        int64   `json:"size,omitempty"`
+	Depth       int     `json:"depth"`
+	Inlinks     int     `json:"inlinks"`
+	Outlinks    int     `json:"outlinks"`
+	PageRank    float64 `json:"pagerank,omitempty"`
+}
+
+type graphLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// visualize serves a force directed drawing of the crawled graph, to explore in a browser, until the listener fails
+func visualize(listener net.Listener, roots []*Page) error {
+	pages := graphPages(roots)
+	inlinks, outlinks := linkDegrees(pages)
+	depths := clickDepths(roots)
+	graph := struct {
+		Nodes []graphNode `json:"nodes"`
+		Links []graphLink `json:"links"`
+	}{Nodes: []graphNode{}, Links: []graphLink{}}
+	for _, page := range pages {
+		graph.Nodes = append(graph.Nodes, graphNode{
+			ID:          page.URL.String(),
+			Status:      page.Status,
+			Error:       page.Error,
+			Title:       page.Title,
+			ContentType: page.ContentType,
+			Size:        page.Size,
+			Depth:       depths[page],
+			Inlinks:     inlinks[page],
+			Outlinks:    outlinks[page],
+			PageRank:    page.PageRank,
+		})
+		for _, link := range page.Links {
+			if link != page {
+				graph.Links = append(graph.Links, graphLink{Source: page.URL.String(), Target: link.URL.String()})
+			}
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(visualizePage)
+	})
+	mux.HandleFunc("GET /graph.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, http.StatusOK, graph)
+	})
+	log.Infof("Visualising the crawl at http://%s/", listener.Addr())
+	return http.Serve(listener, mux)
+}
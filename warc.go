@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// exchange is a page's request and response as they went on the wire, kept for writing it as WARC
+type exchange struct {
+	date           time.Time
+	method         string
+	target         *url.URL //what answered, after any redirects or fallback
+	requestHeader  http.Header
+	proto          string
+	status         int
+	responseHeader http.Header
+	body           []byte //nil for bodies the crawl didn't read, like those of error pages
+}
+
+func newExchange(resp *http.Response) *exchange {
+	requestHeader := resp.Request.Header.Clone()
+	requestHeader.Del("Authorization") //a WARC is handed around, so it shouldn't carry the crawl's credentials
+	requestHeader.Del("Cookie")
+	return &exchange{
+		date:           time.Now().UTC(),
+		method:         resp.Request.Method,
+		target:         resp.Request.URL,
+		requestHeader:  requestHeader,
+		proto:          resp.Proto,
+		status:         resp.StatusCode,
+		responseHeader: resp.Header.Clone(),
+	}
+}
+
+// writeWARC writes the pages under root as WARC records
+func writeWARC(w io.Writer, root *Page) error {
+	return writeWARCs(w, []*Page{root})
+}
+
+// writeWARCs writes a WARC/1.1 file of a warcinfo record and then a response and request record for each page under
+// every root that kept its exchange, which only pages crawled with the crawler's KeepExchanges do
+func writeWARCs(w io.Writer, roots []*Page) error {
+	info := []byte("software: monzo\r\nformat: WARC File Format 1.1\r\n")
+	if err := writeWARCRecord(w, []string{"WARC-Type: warcinfo", "WARC-Record-ID: " + warcRecordID(), "Content-Type: application/warc-fields"}, time.Now().UTC(), info); err != nil {
+		return err
+	}
+	written := 0
+	for _, page := range graphPages(roots) {
+		if page.exchange == nil {
+			continue
+		}
+		if err := page.exchange.write(w); err != nil {
+			return err
+		}
+		written++
+	}
+	if written == 0 {
+		return errors.New("no page kept its request and response, which only a crawl writing WARC does")
+	}
+	return nil
+}
+
+// write writes the exchange as a response record and the request record concurrent to it
+func (e *exchange) write(w io.Writer) error {
+	var response bytes.Buffer
+	fmt.Fprintf(&response, "%s %d %s\r\n", e.proto, e.status, http.StatusText(e.status))
+	header := e.responseHeader.Clone()
+	header.Del("Transfer-Encoding") //the body is kept whole, however it was sent
+	header.Set("Content-Length", strconv.Itoa(len(e.body)))
+	header.Write(&response)
+	response.WriteString("\r\n")
+	response.Write(e.body)
+	responseID := warcRecordID()
+	err := writeWARCRecord(w, []string{
+		"WARC-Type: response",
+		"WARC-Record-ID: " + responseID,
+		"WARC-Target-URI: " + e.target.String(),
+		"WARC-Payload-Digest: " + warcDigest(e.body),
+		"Content-Type: application/http; msgtype=response",
+	}, e.date, response.Bytes())
+	if err != nil {
+		return err
+	}
+	var request bytes.Buffer
+	fmt.Fprintf(&request, "%s %s HTTP/1.1\r\nHost: %s\r\n", e.method, e.target.RequestURI(), e.target.Host)
+	e.requestHeader.Write(&request)
+	request.WriteString("\r\n")
+	return writeWARCRecord(w, []string{
+		"WARC-Type: request",
+		"WARC-Record-ID: " + warcRecordID(),
+		"WARC-Concurrent-To: " + responseID,
+		"WARC-Target-URI: " + e.target.String(),
+		"Content-Type: application/http; msgtype=request",
+	}, e.date, request.Bytes())
+}
+
+// writeWARCRecord writes one record of block, after its fields and the date, digest and length every record has
+func writeWARCRecord(w io.Writer, fields []string, date time.Time, block []byte) error {
+	var record bytes.Buffer
+	record.WriteString("WARC/1.1\r\n")
+	for _, field := range fields {
+		record.WriteString(field + "\r\n")
+	}
+	fmt.Fprintf(&record, "WARC-Date: %s\r\nWARC-Block-Digest: %s\r\nContent-Length: %d\r\n\r\n", date.Format(time.RFC3339), warcDigest(block), len(block))
+	record.Write(block)
+	record.WriteString("\r\n\r\n")
+	_, err := w.Write(record.Bytes())
+	return err
+}
+
+// warcRecordID is a new random UUID as a WARC-Record-ID
+func warcRecordID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 //version 4
+	id[8] = id[8]&0x3f | 0x80 //RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// warcDigest is the SHA-1 of data as WARC digests have it
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// A crawled page comes back out of its WARC as the response it was sent and the request that asked for it
+func TestWARCRoundTrip(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Hello</title></head><body>hello</body></html>`))
+	}))
+	defer site.Close()
+	seed, _ := url.Parse(site.URL + "/")
+	crawler := NewCrawler([]*url.URL{seed}, 1, ScopeHost)
+	crawler.KeepExchanges = true
+	roots := crawler.Run(context.Background())
+	var out bytes.Buffer
+	if err := writeWARCs(&out, roots); err != nil {
+		t.Fatal(err)
+	}
+	records := make(map[string][]byte) //block by WARC-Type
+	reader := bufio.NewReader(&out)
+	for {
+		version, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil || version != "WARC/1.1\r\n" {
+			t.Fatalf("expected a WARC/1.1 record, got %q: %v", version, err)
+		}
+		header, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		block := make([]byte, length+4) //and the two CRLFs ending the record
+		if _, err := io.ReadFull(reader, block); err != nil {
+			t.Fatal(err)
+		}
+		if header.Get("WARC-Type") != "warcinfo" && header.Get("WARC-Target-URI") != seed.String() {
+			t.Errorf("%s record for %s, want %s", header.Get("WARC-Type"), header.Get("WARC-Target-URI"), seed)
+		}
+		records[header.Get("WARC-Type")] = block[:length]
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(records["response"])), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/html" || !bytes.Contains(body, []byte("<title>Hello</title>")) {
+		t.Errorf("unexpected response record: %d %v %q", resp.StatusCode, resp.Header, body)
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(records["request"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodGet || req.URL.Path != "/" || req.Host != seed.Host {
+		t.Errorf("unexpected request record: %s %s on %s", req.Method, req.URL, req.Host)
+	}
+}